@@ -1,10 +1,13 @@
 package main
 
 import (
-	"fmt"
+	"bytes"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -12,58 +15,330 @@ import (
 // User Agent used for requests
 const userAgent = "github.com/Alextopher/aocbot"
 
-// AdventOfCode is an Advent of Code API client
+// DefaultBaseURL is the real Advent of Code
+const DefaultBaseURL = "https://adventofcode.com/"
+
+// Puzzles unlock at midnight EST, which Advent of Code never adjusts for daylight saving
+var est = time.FixedZone("EST", -5*60*60)
+
+// UnlockTime returns when a day's puzzle unlocks
+func UnlockTime(year string, day int) time.Time {
+	y, _ := strconv.Atoi(year)
+	return time.Date(y, time.December, day, 0, 0, 0, 0, est)
+}
+
+// EventDays returns the number of days in an event, which shrank from 25 to 12 in 2025
+func EventDays(year string) int {
+	y, _ := strconv.Atoi(year)
+	if y < 2025 {
+		return 25
+	}
+	return 12
+}
+
+// StarTiers returns the star counts that earn a milestone role in an event of the given length
+//
+// 12 day events have a role every 4 stars (4, 8, ..., 24), longer events every 10 stars (10, 20, ..., 50)
+func StarTiers(days int) []int {
+	step := 4
+	if days > 12 {
+		step = 10
+	}
+
+	var tiers []int
+	for stars := step; stars <= 2*days; stars += step {
+		tiers = append(tiers, stars)
+	}
+	return tiers
+}
+
+// MinRefreshInterval is how often a leaderboard may be fetched, Advent of Code asks bots to wait at least 15 minutes
+const MinRefreshInterval = 15 * time.Minute
+
+// leaderboardKey identifies a leaderboard for a single event, as seen through one session
+type leaderboardKey struct {
+	session string
+	id      string
+	year    string
+}
+
+// cachedLeaderboard is the most recent fetch of a leaderboard
+type cachedLeaderboard struct {
+	leaderboard *Leaderboard
+	fetched     time.Time
+
+	// Failed fetches count towards the refresh interval too
+	attempted time.Time
+
+	// Closed when the in-flight fetch (if any) completes
+	inflight chan struct{}
+	err      error
+}
+
+// AdventOfCode is a process-wide Advent of Code API client
+//
+// Leaderboards are cached by (session, leaderboard id, year) and shared between every guild that follows them. Each
+// one is fetched at most once per MinRefreshInterval, and concurrent requests for the same leaderboard share one fetch.
+//
+// Sessions are named so that their cookies can be replaced, and so that cookies never appear in keys or logs. The
+// default session "" uses the global cookie, guilds with their own cookie use a session named after the guild.
 type AdventOfCode struct {
-	sync.RWMutex
+	sync.Mutex
 
-	id            string
-	sessionCookie string
+	// Session names to session cookies
+	sessions map[string]string
+	baseURL  string
+
+	// Where raw leaderboard responses are cached, see cache.go
+	cacheDir string
+
+	leaderboards map[leaderboardKey]*cachedLeaderboard
+
+	// Called with the changes every time a leaderboard is updated
+	listeners map[leaderboardKey][]*subscription
+}
 
-	leaderboards map[string]*Leaderboard
-	lastUpdated  time.Time
+// subscription is a listener registered with Subscribe, it is a pointer so that it can be told apart from the others
+type subscription struct {
+	listener func(events []LeaderboardEvent)
 }
 
-// NewAdventOfCode creates a new Advent of Code API
-func NewAdventOfCode(sessionCookie string, id string) *AdventOfCode {
+// NewAdventOfCode creates a new Advent of Code API, an empty baseURL uses DefaultBaseURL
+func NewAdventOfCode(sessionCookie string, baseURL string, cacheDir string) *AdventOfCode {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
 	return &AdventOfCode{
-		sessionCookie: sessionCookie,
-		leaderboards:  make(map[string]*Leaderboard),
-		id:            id,
+		sessions:     map[string]string{"": sessionCookie},
+		baseURL:      baseURL,
+		cacheDir:     cacheDir,
+		leaderboards: make(map[leaderboardKey]*cachedLeaderboard),
+		listeners:    make(map[leaderboardKey][]*subscription),
+	}
+}
+
+// SetSession sets the cookie of a named session
+func (aoc *AdventOfCode) SetSession(session, cookie string) {
+	aoc.Lock()
+	aoc.sessions[session] = cookie
+	aoc.Unlock()
+}
+
+// Subscribe registers a function to be called with the events produced by each update of a leaderboard
+//
+// The returned function unregisters it again
+func (aoc *AdventOfCode) Subscribe(session, id, year string, listener func(events []LeaderboardEvent)) func() {
+	key := leaderboardKey{session, id, year}
+	sub := &subscription{listener}
+
+	aoc.Lock()
+	aoc.listeners[key] = append(aoc.listeners[key], sub)
+	aoc.Unlock()
+
+	return func() {
+		aoc.Lock()
+		defer aoc.Unlock()
+
+		// Copied rather than deleted in place, store may be going through the old slice
+		listeners := slices.DeleteFunc(slices.Clone(aoc.listeners[key]), func(other *subscription) bool {
+			return other == sub
+		})
+		if len(listeners) == 0 {
+			delete(aoc.listeners, key)
+		} else {
+			aoc.listeners[key] = listeners
+		}
 	}
 }
 
-// GetLeaderboard gets the most recent leaderboard data from the API
-func (aoc *AdventOfCode) GetLeaderboard(year string) *Leaderboard {
-	if time.Since(aoc.lastUpdated) > 15*time.Minute {
-		aoc.UpdateLeaderboard(year)
+// GetLeaderboard gets the most recent leaderboard data, refreshing it first if it is due
+//
+// If refreshing fails the previous data is returned, which is nil if the leaderboard was never fetched
+func (aoc *AdventOfCode) GetLeaderboard(session, id, year string) *Leaderboard {
+	err := aoc.Refresh(session, id, year)
+	if err != nil {
+		log.Printf("Error refreshing leaderboard (%s, %s): %s\n", id, year, err)
 	}
 
-	aoc.RLock()
-	leaderboard, ok := aoc.leaderboards[year]
-	if !ok {
+	leaderboard := aoc.Cached(session, id, year)
+	if leaderboard == nil {
 		log.Println("Leaderboard not found for year: ", year)
 	}
-	aoc.RUnlock()
 
 	return leaderboard
 }
 
-// UpdateLeaderboard updates a leaderboard by getting the latest data from the API
-func (aoc *AdventOfCode) UpdateLeaderboard(year string) error {
-	requestURL := "https://adventofcode.com/" + year + "/leaderboard/private/view/" + aoc.id + ".json"
-	fmt.Println(requestURL)
+// Cached gets the most recent leaderboard data without refreshing it, nil if it was never fetched
+func (aoc *AdventOfCode) Cached(session, id, year string) *Leaderboard {
+	aoc.Lock()
+	defer aoc.Unlock()
+
+	cached, ok := aoc.leaderboards[leaderboardKey{session, id, year}]
+	if !ok {
+		return nil
+	}
+
+	return cached.leaderboard
+}
+
+// Status reports when a leaderboard was last fetched, and whether it is stale because the latest fetch failed
+func (aoc *AdventOfCode) Status(session, id, year string) (time.Time, bool) {
+	aoc.Lock()
+	defer aoc.Unlock()
+
+	cached, ok := aoc.leaderboards[leaderboardKey{session, id, year}]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return cached.fetched, cached.err != nil
+}
+
+// Refresh fetches a leaderboard from the API, unless it was fetched less than MinRefreshInterval ago
+//
+// If a fetch of the same leaderboard is already in flight, this waits for it and returns its result
+func (aoc *AdventOfCode) Refresh(session, id, year string) error {
+	key := leaderboardKey{session, id, year}
+
+	aoc.Lock()
+	cached, ok := aoc.leaderboards[key]
+	if !ok {
+		cached = aoc.loadCache(key)
+		aoc.leaderboards[key] = cached
+	}
+
+	if inflight := cached.inflight; inflight != nil {
+		aoc.Unlock()
+		<-inflight
+
+		aoc.Lock()
+		err := cached.err
+		aoc.Unlock()
+		return err
+	}
+
+	if !cached.attempted.IsZero() && time.Since(cached.attempted) < MinRefreshInterval {
+		err := cached.err
+		aoc.Unlock()
+		return err
+	}
+
+	inflight := make(chan struct{})
+	cached.inflight = inflight
+	started := time.Now()
+	cached.attempted = started
+	cookie := aoc.sessions[session]
+	aoc.Unlock()
+
+	leaderboard, raw, err := aoc.fetch(id, year, cookie)
+	events, listeners := aoc.store(key, cached, started, leaderboard, raw, err)
+
+	aoc.Lock()
+	cached.inflight = nil
+	aoc.Unlock()
+	close(inflight)
+
+	// Listeners are only told once the fetch is done, since they may well read the leaderboard themselves
+	notify(listeners, events)
+
+	return err
+}
+
+// UpdateSession replaces a session's cookie, if it is able to fetch the given leaderboard
+//
+// The leaderboard fetched to validate the cookie is stored like any other fetch, and leaderboards that the old
+// cookie was rejected for may be fetched again straight away.
+func (aoc *AdventOfCode) UpdateSession(session, cookie, id, year string) error {
+	key := leaderboardKey{session, id, year}
+	started := time.Now()
+
+	leaderboard, raw, err := aoc.fetch(id, year, cookie)
+	if err != nil {
+		return err
+	}
+
+	aoc.Lock()
+	aoc.sessions[session] = cookie
+	for key, cached := range aoc.leaderboards {
+		if key.session == session && cached.err == ErrInvalidSession {
+			cached.attempted = time.Time{}
+		}
+	}
+
+	cached, ok := aoc.leaderboards[key]
+	if !ok {
+		cached = aoc.loadCache(key)
+		aoc.leaderboards[key] = cached
+	}
+	cached.attempted = started
+	aoc.Unlock()
+
+	events, listeners := aoc.store(key, cached, started, leaderboard, raw, nil)
+	notify(listeners, events)
+
+	return nil
+}
+
+// store records the result of a fetch, and returns the changes along with the listeners to notify of them
+func (aoc *AdventOfCode) store(key leaderboardKey, cached *cachedLeaderboard, started time.Time, leaderboard *Leaderboard, raw []byte, err error) ([]LeaderboardEvent, []*subscription) {
+	aoc.Lock()
+	var events []LeaderboardEvent
+	cached.err = err
+	if err == nil {
+		events = DiffLeaderboards(cached.leaderboard, leaderboard)
+		cached.leaderboard = leaderboard
+		cached.fetched = started
+	}
+	listeners := aoc.listeners[key]
+	aoc.Unlock()
+
+	if err != nil {
+		return nil, nil
+	}
+
+	log.Printf("Updated leaderboard for (%s, %s)\n", key.id, key.year)
+
+	err = aoc.saveCache(key, raw, started)
+	if err != nil {
+		log.Println("Error writing leaderboard cache: ", err)
+	}
+
+	return events, listeners
+}
+
+// notify calls each listener with the changes of an update, if there are any
+func notify(listeners []*subscription, events []LeaderboardEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	for _, sub := range listeners {
+		sub.listener(events)
+	}
+}
+
+// fetch gets a leaderboard from the API
+//
+// The raw response is returned too, so that it can be cached
+func (aoc *AdventOfCode) fetch(id, year, cookie string) (*Leaderboard, []byte, error) {
+	requestURL, err := url.JoinPath(aoc.baseURL, year, "leaderboard/private/view", id+".json")
+	if err != nil {
+		log.Println("Error while building URL: ", err)
+		return nil, nil, err
+	}
 
 	url, err := url.Parse(requestURL)
 	if err != nil {
 		log.Println("Error while parsing URL: ", err)
-		return err
+		return nil, nil, err
 	}
 
 	request := http.Request{
 		Method: "GET",
 		URL:    url,
 		Header: http.Header{
-			"Cookie":     []string{"session=" + aoc.sessionCookie},
+			"Cookie":     []string{"session=" + cookie},
 			"User-Agent": []string{userAgent},
 		},
 	}
@@ -71,26 +346,27 @@ func (aoc *AdventOfCode) UpdateLeaderboard(year string) error {
 	response, err := http.DefaultClient.Do(&request)
 	if err != nil {
 		log.Println("Error while making request: ", err)
-		return err
+		return nil, nil, err
 	}
+	defer response.Body.Close()
 
 	// Check the content type of the response, if it's not JSON then we can't parse it
 	contentType := response.Header.Get("Content-Type")
 	if contentType != "application/json" {
-		return ErrInvalidSession
+		return nil, nil, ErrInvalidSession
 	}
 
-	leaderboard, err := ParseLeaderboard(response.Body)
+	raw, err := io.ReadAll(response.Body)
 	if err != nil {
-		log.Println("Error while parsing response: ", err)
-		return err
+		log.Println("Error while reading response: ", err)
+		return nil, nil, err
 	}
 
-	aoc.Lock()
-	aoc.leaderboards[year] = leaderboard
-	aoc.lastUpdated = time.Now()
-	aoc.Unlock()
+	leaderboard, err := ParseLeaderboard(bytes.NewReader(raw))
+	if err != nil {
+		log.Println("Error while parsing response: ", err)
+		return nil, nil, err
+	}
 
-	log.Printf("Updated leaderboard for (%s, %s)\n", aoc.id, year)
-	return nil
+	return leaderboard, raw, nil
 }
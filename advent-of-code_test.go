@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// skipThrottle lets a leaderboard be fetched again straight away, as if MinRefreshInterval had passed
+func skipThrottle(aoc *AdventOfCode, session, id, year string) {
+	aoc.Lock()
+	aoc.leaderboards[leaderboardKey{session, id, year}].attempted = time.Time{}
+	aoc.Unlock()
+}
+
+func TestListenerReadsLeaderboard(t *testing.T) {
+	// Every fetch finds alice with one more star
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(fetches.Add(1))
+
+		var stars []star
+		for day := 1; day <= n; day++ {
+			stars = append(stars, star{day, 1, 100 * day})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testLeaderboard("2024", testMember(1, "alice", stars...)))
+	}))
+	defer server.Close()
+
+	aoc := NewAdventOfCode("cookie", server.URL, t.TempDir())
+	if err := aoc.Refresh("", "1", "2024"); err != nil {
+		t.Fatalf("Refresh() returned %v", err)
+	}
+
+	// Listeners like Bot.subscribe read the leaderboard they were notified about
+	seen := make(chan *Leaderboard, 1)
+	aoc.Subscribe("", "1", "2024", func([]LeaderboardEvent) {
+		seen <- aoc.GetLeaderboard("", "1", "2024")
+	})
+
+	skipThrottle(aoc, "", "1", "2024")
+
+	done := make(chan error)
+	go func() { done <- aoc.Refresh("", "1", "2024") }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Refresh() returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Refresh() didn't return, the listener is stuck waiting on the fetch that notified it")
+	}
+
+	if leaderboard := <-seen; leaderboard.Members["1"].Stars != 2 {
+		t.Errorf("the listener read a leaderboard where alice has %d stars, want 2", leaderboard.Members["1"].Stars)
+	}
+}
+
+func TestUpdateSessionNotifiesListeners(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "renewed" {
+			w.Header().Set("Content-Type", "text/html")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testLeaderboard("2024", testMember(1, "alice", star{1, 1, 100})))
+	}))
+	defer server.Close()
+
+	aoc := NewAdventOfCode("expired", server.URL, t.TempDir())
+	setCached(aoc, "1", testLeaderboard("2024", testMember(1, "alice")))
+
+	seen := make(chan *Leaderboard, 1)
+	aoc.Subscribe("", "1", "2024", func([]LeaderboardEvent) {
+		seen <- aoc.GetLeaderboard("", "1", "2024")
+	})
+
+	if err := aoc.UpdateSession("", "expired", "1", "2024"); err != ErrInvalidSession {
+		t.Errorf("UpdateSession() with an expired cookie returned %v, want ErrInvalidSession", err)
+	}
+
+	if err := aoc.UpdateSession("", "renewed", "1", "2024"); err != nil {
+		t.Fatalf("UpdateSession() returned %v", err)
+	}
+
+	select {
+	case leaderboard := <-seen:
+		if leaderboard.Members["1"].Stars != 1 {
+			t.Errorf("the listener read a leaderboard where alice has %d stars, want 1", leaderboard.Members["1"].Stars)
+		}
+	default:
+		t.Error("the listener wasn't notified of the star fetched with the new session")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord rejects messages longer than this
+const maxMessageLength = 2000
+
+// announceStars posts every star earned by a linked member in a single message
+func (bot *Bot) announceStars(guildState *GuildState, events []LeaderboardEvent) {
+	if guildState.announceChannelID == "" {
+		return
+	}
+
+	year := guildState.current.Load().year
+
+	var lines []string
+	for _, event := range events {
+		star, ok := event.(StarEvent)
+		if !ok {
+			continue
+		}
+
+		discordID, ok := guildState.db.GetDiscordID(star.ID)
+		if !ok {
+			continue
+		}
+
+		solveTime := time.Unix(star.Timestamp, 0).Sub(UnlockTime(year, star.Day))
+		lines = append(lines, fmt.Sprintf(":star: <@%s> earned day %d part %d in %s", discordID, star.Day, star.Part, formatDuration(solveTime)))
+	}
+
+	bot.announce(guildState.announceChannelID, lines)
+}
+
+// announceMilestone congratulates a member for reaching a new tier role
+//
+// The message goes to the announcement channel, and to the member directly if they opted in with `/dms`
+func (bot *Bot) announceMilestone(guildState *GuildState, guildMember *discordgo.Member, role ManagedRole) {
+	if guildState.announceChannelID != "" {
+		msg := fmt.Sprintf(":tada: Congratulations <@%s> for reaching **%s**!", guildMember.User.ID, role.Name)
+		bot.announce(guildState.announceChannelID, []string{msg})
+	}
+
+	if !guildState.db.WantsDirectMessages(guildMember.User.ID) {
+		return
+	}
+
+	msg := fmt.Sprintf(":tada: Congratulations, you have earned the **%s** role!", role.Name)
+	err := bot.directMessage(guildMember.User.ID, msg)
+	if err != nil {
+		log.Println("Error (announceMilestone) sending DM: ", err)
+	}
+}
+
+// directMessage sends a message to a user directly
+func (bot *Bot) directMessage(userID string, msg string) error {
+	channel, err := bot.session.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = bot.session.ChannelMessageSend(channel.ID, msg)
+	return err
+}
+
+// announce posts lines to a channel, using as few messages as possible
+//
+// Mentions are rendered but never ping anyone
+func (bot *Bot) announce(channelID string, lines []string) {
+	for _, content := range chunkLines(lines, maxMessageLength) {
+		_, err := bot.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content:         content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+
+		if err != nil {
+			log.Println("Error (announce) sending message: ", err)
+			return
+		}
+	}
+}
+
+// chunkLines joins lines into messages no longer than limit
+func chunkLines(lines []string, limit int) []string {
+	var chunks []string
+	var sb strings.Builder
+
+	for _, line := range lines {
+		if sb.Len() > 0 && sb.Len()+len(line)+1 > limit {
+			chunks = append(chunks, sb.String())
+			sb.Reset()
+		}
+
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(line)
+	}
+
+	if sb.Len() > 0 {
+		chunks = append(chunks, sb.String())
+	}
+
+	return chunks
+}
+
+// formatDuration formats a duration as days, hours and minutes, e.g. "1d 2h 3m"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
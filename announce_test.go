@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunkLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		limit int
+		want  []string
+	}{
+		{"empty", nil, 10, nil},
+		{"fits", []string{"abc", "def"}, 7, []string{"abc\ndef"}},
+		{"splits", []string{"abc", "def", "ghi"}, 6, []string{"abc", "def", "ghi"}},
+		{"fills each chunk", []string{"ab", "cd", "ef", "gh"}, 8, []string{"ab\ncd\nef", "gh"}},
+		{"line longer than limit", []string{"abcdefgh", "ij"}, 4, []string{"abcdefgh", "ij"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := chunkLines(test.lines, test.limit)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("chunkLines(%q, %d) = %q, want %q", test.lines, test.limit, got, test.want)
+			}
+		})
+	}
+}
+
+func TestChunkLinesKeepsEveryLine(t *testing.T) {
+	var lines []string
+	for range 500 {
+		lines = append(lines, ":star: <@123456789012345678> earned day 12 part 2 in 1d 2h 3m")
+	}
+
+	chunks := chunkLines(lines, maxMessageLength)
+	for _, chunk := range chunks {
+		if len(chunk) > maxMessageLength {
+			t.Errorf("chunk of %d bytes is longer than %d", len(chunk), maxMessageLength)
+		}
+	}
+
+	if got := strings.Split(strings.Join(chunks, "\n"), "\n"); !slices.Equal(got, lines) {
+		t.Errorf("chunks hold %d lines, want %d", len(got), len(lines))
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0m"},
+		{29 * time.Second, "0m"},
+		{30 * time.Second, "1m"},
+		{59 * time.Minute, "59m"},
+		{time.Hour, "1h 0m"},
+		{2*time.Hour + 3*time.Minute, "2h 3m"},
+		{24 * time.Hour, "1d 0h 0m"},
+		{26*time.Hour + 3*time.Minute + 40*time.Second, "1d 2h 4m"},
+	}
+
+	for _, test := range tests {
+		if got := formatDuration(test.d); got != test.want {
+			t.Errorf("formatDuration(%s) = %q, want %q", test.d, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// requestClaim records a claim that needs an admin's approval, and asks for it in the admin channel
+//
+// When the admin channel can't be reached the request is withdrawn again, so that it can be made again later
+func (bot *Bot) requestClaim(guildState *GuildState, discordID string, member *Member) error {
+	adventID := fmt.Sprint(member.ID)
+
+	err := guildState.db.RequestClaim(discordID, adventID, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	// Buttons carry the account, so that a request that was replaced can't be approved by mistake
+	arg := discordID + ":" + adventID
+
+	_, err = bot.session.ChannelMessageSendComplex(guildState.adminChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("<@%s> wants to claim **%s** (ID %s, %d stars).", discordID, member.DisplayName(), adventID, member.Stars),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Approve", Style: discordgo.SuccessButton, CustomID: "approve:" + arg},
+					discordgo.Button{Label: "Reject", Style: discordgo.DangerButton, CustomID: "reject:" + arg},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Join(err, guildState.db.WithdrawClaim(discordID, adventID))
+	}
+
+	return nil
+}
+
+// onClaimDecision handles an admin pressing Approve or Reject on a claim request
+func (bot *Bot) onClaimDecision(interaction *discordgo.Interaction, approved bool, arg string) {
+	deferred := bot.deferUpdate(interaction)
+
+	discordID, adventID, _ := strings.Cut(arg, ":")
+	original := interaction.Message.Content
+
+	if !bot.IsAdmin(interaction.Member) {
+		deferred.followup("Error 38: You must be an admin to approve or reject claims.")
+		return
+	}
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalizeMessage("Error 39: This guild is not configured, yet.", nil)
+		return
+	}
+
+	request, ok := guildState.db.GetClaimRequest(discordID)
+	if !ok || request.AdventID != adventID {
+		deferred.finalizeMessage(original+"\nThis request was replaced or has already been decided.", nil)
+		return
+	}
+
+	decision := EventClaimDecision{DiscordID: discordID, AdventID: adventID, AdminID: interaction.Member.User.ID}
+	err := guildState.db.DecideClaim(decision, approved)
+	if err == ErrAlreadyClaimed {
+		deferred.followup("Error 40: Someone else has claimed this account since, it can only be rejected.")
+		return
+	} else if err == ErrHasClaim {
+		deferred.followup("Error 48: They have claimed another account since, this request can only be rejected.")
+		return
+	} else if err != nil {
+		log.Println("Error (onClaimDecision) saving decision: ", err)
+		deferred.followup("Error 41: Something went wrong, please try again later.")
+		return
+	}
+
+	log.Printf("Claim of %s by %s approved=%t by @%s", adventID, discordID, approved, interaction.Member.User.Username)
+
+	verdict, msg := "Rejected", "Your claim of an Advent of Code account was rejected by an admin, if you believe this is an error, please contact them."
+	if approved {
+		verdict, msg = "Approved", "Success: Your claim of an Advent of Code account was approved!"
+	}
+	deferred.finalizeMessage(fmt.Sprintf("%s\n%s by <@%s>.", original, verdict, interaction.Member.User.ID), nil)
+
+	err = bot.directMessage(discordID, msg)
+	if err != nil {
+		log.Println("Error (onClaimDecision) sending DM: ", err)
+	}
+
+	if !approved {
+		return
+	}
+
+	guild, err := bot.session.State.Guild(interaction.GuildID)
+	if err != nil {
+		log.Println("Error (onClaimDecision) getting guild: ", err)
+		return
+	}
+
+	guildMember, err := bot.session.GuildMember(guild.ID, discordID)
+	if err != nil {
+		log.Println("Error (onClaimDecision) getting guild member: ", err)
+		return
+	}
+
+	err = bot.SyncMemberRoles(guild, guildMember)
+	if err != nil {
+		log.Println("Error (onClaimDecision) syncing roles: ", err)
+	}
+}
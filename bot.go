@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -17,16 +19,27 @@ type Bot struct {
 	// Each discord server has its own state
 	states map[string]*GuildState
 
-	// The Advent of Code API session cookie
-	sessionCookie string
+	// The Advent of Code API client, shared by every guild
+	adventOfCode *AdventOfCode
+
+	// Runs role syncs
+	syncPool *SyncPool
+
+	// The config, and where to save it when it is changed at runtime. Changes hold configLock, handlers run concurrently.
+	config     *Config
+	configPath string
+	configLock sync.Mutex
 }
 
 // NewBot creates a new bot
-func NewBot(session *discordgo.Session, sessionCookie string) *Bot {
+func NewBot(session *discordgo.Session, config *Config, configPath string) *Bot {
 	return &Bot{
-		session:       session,
-		states:        make(map[string]*GuildState),
-		sessionCookie: sessionCookie,
+		session:      session,
+		states:       make(map[string]*GuildState),
+		adventOfCode: NewAdventOfCode(config.SessionCookie, config.AdventOfCodeURL, "logs"),
+		syncPool:     NewSyncPool(config.SyncConcurrency),
+		config:       config,
+		configPath:   configPath,
 	}
 }
 
@@ -38,12 +51,54 @@ func (bot *Bot) AddGuild(guildID string, guildConfig GuildConfig) (err error) {
 		return err
 	}
 
-	bot.states[guildID], err = NewGuildState(bot.sessionCookie, guildConfig, logFile)
+	guildState, err := NewGuildState(bot.adventOfCode, guildID, guildConfig, logFile)
 	if err != nil {
 		return err
 	}
+	bot.states[guildID] = guildState
+
+	bot.subscribe(guildID, guildState, guildState.current.Load().year)
+
+	// Keep running when Advent of Code can't be reached, the cached copy (if any) is served until it can
+	err = bot.refreshGuild(guildID, guildState)
+	if err != nil {
+		log.Println("Error (AddGuild) refreshing leaderboard: ", err)
+	}
+
+	// Whatever was there at startup isn't news
+	guildState.Changes()
 
-	return bot.states[guildID].adventOfCode.UpdateLeaderboard(guildConfig.Year)
+	return nil
+}
+
+// subscribe reacts to changes in a year of the guild's leaderboards, instead of the year it reacted to before
+//
+// Changes are diffed per guild rather than per leaderboard, see GuildState.Changes
+func (bot *Bot) subscribe(guildID string, guildState *GuildState, year string) {
+	session := guildState.Session()
+
+	var unsubscribes []func()
+	for _, id := range guildState.leaderboardIDs {
+		unsubscribe := bot.adventOfCode.Subscribe(session, id, year, func([]LeaderboardEvent) {
+			if events := guildState.Changes(); len(events) > 0 {
+				bot.onLeaderboardEvents(guildID, events)
+			}
+		})
+		unsubscribes = append(unsubscribes, unsubscribe)
+	}
+
+	guildState.Lock()
+	previous := guildState.unsubscribe
+	guildState.unsubscribe = func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	}
+	guildState.Unlock()
+
+	if previous != nil {
+		previous()
+	}
 }
 
 // Start starts the bot (and waits for it to be ready)
@@ -66,122 +121,165 @@ func (bot *Bot) Start() error {
 }
 
 // Sync syncs the bot with the Advent of Code API
+//
+// Only members whose progress changed are synced, see onLeaderboardEvents. Guilds that follow the calendar move on to
+// each new event here, see rollover.
 func (bot *Bot) Sync() {
 	for _, guild := range bot.session.State.Guilds {
-		err := bot.SyncAllRoles(guild)
+		guildState, ok := bot.states[guild.ID]
+		if !ok {
+			continue
+		}
+
+		err := bot.rollover(guild, guildState, time.Now())
 		if err != nil {
-			log.Println("Error (Sync) syncing roles: ", err)
+			log.Println("Error (Sync) starting new season: ", err)
 		}
-	}
-}
 
-// CreateRoles ensure that the server has the required roles
-//
-// 1 role for each day + 1 role for 10, 20, 30, 40, and 50 stars
-func (bot *Bot) CreateRoles(guild *discordgo.Guild) error {
-	True := true
+		err = bot.refreshGuild(guild.ID, guildState)
+		if err != nil {
+			log.Println("Error (Sync) updating leaderboard: ", err)
+		}
 
-	// Get the guild state
-	guildState, ok := bot.states[guild.ID]
-	if !ok {
-		return ErrNotConfigured
+		bot.checkVerifications(guild, guildState, time.Now())
 	}
+}
 
-	// Spoiler role (allows users to access all channels)
-	if !bot.CheckRole(guild, "Spoiler") {
-		_, err := bot.session.GuildRoleCreate(guild.ID, &discordgo.RoleParams{
-			Name:        "Spoiler",
-			Mentionable: &True,
-		})
-
+// SyncAll syncs every claimed member's roles, regardless of whether their progress changed
+func (bot *Bot) SyncAll() {
+	for _, guild := range bot.session.State.Guilds {
+		err := bot.SyncAllRoles(guild)
 		if err != nil {
-			return err
+			log.Println("Error (SyncAll) syncing roles: ", err)
 		}
 	}
+}
 
-	// now there are only 12*2=24 stars
-	colors := map[string]int{
-		"24 Stars":  0xF1C40F, // Gold
-		"20 Stars":  0xE91E63, // Red/Pink
-		"16 Stars":  0x9B59B6, // Purple
-		"12 Stars":  0x3498DB, // Blue
-		"8 Stars":   0x1ABC9C, // Teal
-		"4 Stars":   0x2ECC71, // Green
-		"Connected": 0x1ABC9C, // Less Green
+// onLeaderboardEvents reacts to the changes found by a leaderboard update
+func (bot *Bot) onLeaderboardEvents(guildID string, events []LeaderboardEvent) {
+	guild, err := bot.session.State.Guild(guildID)
+	if err != nil {
+		log.Println("Error (onLeaderboardEvents) getting guild: ", err)
+		return
 	}
 
-	for name, color := range colors {
-		if !bot.CheckRole(guild, name) {
-			_, err := bot.session.GuildRoleCreate(guild.ID, &discordgo.RoleParams{
-				Name:        name,
-				Color:       &color,
-				Mentionable: &True,
-				Hoist:       &True,
-			})
+	guildState := bot.states[guildID]
 
-			if err != nil {
-				return err
-			}
+	bot.announceStars(guildState, events)
+
+	// Sync the roles of each claimed member that earned a star or (re)joined the leaderboard, and take the managed
+	// roles away from those who left. Their claim is kept in case they come back.
+	claims := make(map[string]string)
+	var left []string
+	for _, event := range events {
+		discordID, ok := guildState.db.GetDiscordID(event.MemberID())
+		if !ok {
+			continue
 		}
-	}
 
-	if guildState.daily_roles {
-		for i := 12; i > 0; i-- {
-			name := fmt.Sprintf("Day %02d", i)
+		switch event.(type) {
+		case StarEvent, JoinEvent:
+			claims[discordID] = event.MemberID()
+		case LeaveEvent:
+			left = append(left, discordID)
+		}
+	}
 
-			if !bot.CheckRole(guild, name) {
-				_, err := bot.session.GuildRoleCreate(guild.ID, &discordgo.RoleParams{
-					Name: name,
-				})
+	for _, discordID := range left {
+		guildMember, err := bot.session.GuildMember(guild.ID, discordID)
+		if err != nil {
+			log.Println("Error (onLeaderboardEvents) getting guild member: ", err)
+			continue
+		}
 
-				if err != nil {
-					return err
-				}
-			}
+		err = bot.RemoveAllRoles(guild, guildMember)
+		if err != nil {
+			log.Println("Error (onLeaderboardEvents) removing roles: ", err)
 		}
 	}
 
-	return nil
+	if len(claims) == 0 {
+		return
+	}
+
+	// Changes can arrive while the guild is already syncing, those are synced once it is done
+	jobs, err := bot.claimJobs(guild, guildState, claims)
+	if err != nil {
+		log.Println("Error (onLeaderboardEvents) syncing roles: ", err)
+		return
+	}
+
+	bot.syncPool.Queue(guild.ID, jobs)
 }
 
-// CheckRole checks if a role exists
-func (bot *Bot) CheckRole(guild *discordgo.Guild, name string) bool {
-	for _, role := range guild.Roles {
-		if role.Name == name {
-			return true
-		}
+// CreateRoles ensure that the server has the required roles
+//
+// See NewRoleRegistry for the roles that are managed
+func (bot *Bot) CreateRoles(guild *discordgo.Guild) error {
+	// Get the guild state
+	guildState, ok := bot.states[guild.ID]
+	if !ok {
+		return ErrNotConfigured
 	}
 
-	return false
+	return guildState.current.Load().roles.Ensure(bot.session, guild)
 }
 
 // SetupChannel sets up a channel for use by a given day (and spoiler)
 func (bot *Bot) SetupChannel(guild *discordgo.Guild, channelID string, day int64) error {
+	guildState, ok := bot.states[guild.ID]
+	if !ok {
+		return ErrNotConfigured
+	}
+
+	registry := guildState.current.Load().roles
+
 	// Get the day role
-	roleName := fmt.Sprintf("Day %02d", day)
-	var roleID string
-	for _, role := range guild.Roles {
-		if role.Name == roleName {
-			roleID = role.ID
-			break
-		}
+	roleID, ok := registry.ID(guild, DayRoleKey(int(day)))
+	if !ok {
+		return ErrDoesNotExist
 	}
 
 	// Get the spoiler role
-	var spoilerID string
-	for _, role := range guild.Roles {
-		if role.Name == "Spoiler" {
-			spoilerID = role.ID
-			break
-		}
+	spoilerID, ok := registry.ID(guild, SpoilerRoleKey)
+	if !ok {
+		return ErrDoesNotExist
 	}
 
 	// Get the everyone role
-	var everyoneID string
-	for _, role := range guild.Roles {
-		if role.Name == "@everyone" {
-			everyoneID = role.ID
-			break
+	everyoneID := everyoneRoleID(guild)
+
+	// Remember how @everyone was set up before the first time this channel was set up, so teardown can revert it
+	record, ok := guildState.db.GetChannel(channelID)
+	if !ok {
+		channel, err := bot.session.Channel(channelID)
+		if err != nil {
+			return err
+		}
+
+		// A channel that already lets the spoiler role in was set up before channels were recorded, by then the
+		// original @everyone overwrite had already been replaced with ours
+		record = EventChannel{ChannelID: channelID}
+		for _, overwrite := range channel.PermissionOverwrites {
+			if overwrite.ID == spoilerID {
+				record.EveryoneUnknown = true
+			}
+		}
+
+		for _, overwrite := range channel.PermissionOverwrites {
+			if overwrite.ID == everyoneID && !record.EveryoneUnknown {
+				record.HadEveryone = true
+				record.EveryoneAllow = overwrite.Allow
+				record.EveryoneDeny = overwrite.Deny
+			}
+		}
+	}
+
+	if !ok || record.Day != int(day) {
+		record.Day = int(day)
+		err := guildState.db.SetChannel(record)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -192,6 +290,18 @@ func (bot *Bot) SetupChannel(guild *discordgo.Guild, channelID string, day int64
 	return nil
 }
 
+// everyoneRoleID gets the id of the @everyone role
+func everyoneRoleID(guild *discordgo.Guild) string {
+	for _, role := range guild.Roles {
+		if role.Name == "@everyone" {
+			return role.ID
+		}
+	}
+
+	// Discord gives @everyone the same id as the guild
+	return guild.ID
+}
+
 // SyncMemberRoles syncs a user's roles to reflect their current star count.
 func (bot *Bot) SyncMemberRoles(guild *discordgo.Guild, guildMember *discordgo.Member) (err error) {
 	guildState, ok := bot.states[guild.ID]
@@ -213,7 +323,7 @@ func (bot *Bot) SyncMemberRoles(guild *discordgo.Guild, guildMember *discordgo.M
 		return ErrDoesNotExist
 	}
 
-	return bot.syncRoles(guild, guildMember, member, guildState.daily_roles)
+	return bot.syncRoles(guild, guildState, guildMember, member, guildState.scorer.Scores(leaderboard))
 }
 
 // SyncAllRoles updates each user's roles to reflect their current star count.
@@ -223,128 +333,185 @@ func (bot *Bot) SyncAllRoles(guild *discordgo.Guild) error {
 		return ErrNotConfigured
 	}
 
+	log.Printf("Syncing roles for %s %t\n", guild.Name, guildState.daily_roles)
+	_, err := bot.syncClaims(guild, guildState, guildState.db.GetClaims())
+	return err
+}
+
+// syncClaims syncs the roles of the given claims (discord id to advent id) on the sync pool
+func (bot *Bot) syncClaims(guild *discordgo.Guild, guildState *GuildState, claims map[string]string) (SyncReport, error) {
+	if len(claims) == 0 {
+		return SyncReport{GuildID: guild.ID}, nil
+	}
+
+	jobs, err := bot.claimJobs(guild, guildState, claims)
+	if err != nil {
+		return SyncReport{GuildID: guild.ID}, err
+	}
+
+	return bot.syncPool.Run(guild.ID, jobs)
+}
+
+// claimJobs creates a sync job for each of the given claims (discord id to advent id)
+func (bot *Bot) claimJobs(guild *discordgo.Guild, guildState *GuildState, claims map[string]string) ([]func() error, error) {
 	// Get the leaderboard
 	leaderboard := guildState.GetLeaderboard()
+	if leaderboard == nil {
+		return nil, ErrDoesNotExist
+	}
 
-	log.Printf("Syncing roles for %s %t\n", guild.Name, guildState.daily_roles)
-	guildState.db.GoForEach(func(discord_id, advent_id string) {
-		member, ok := leaderboard.GetMemberByID(advent_id)
-		if !ok {
-			log.Printf("Error (SyncAllRoles): Member %s not found\n", advent_id)
-			return
+	// Some modes score members against each other, so everyone is scored once for every job
+	scores := guildState.scorer.Scores(leaderboard)
+
+	jobs := make([]func() error, 0, len(claims))
+	for discordID, adventID := range claims {
+		jobs = append(jobs, func() error {
+			member, ok := leaderboard.GetMemberByID(adventID)
+			if !ok {
+				return fmt.Errorf("member %s: %w", adventID, ErrDoesNotExist)
+			}
+
+			// Get the member
+			guildMember, err := bot.session.GuildMember(guild.ID, discordID)
+			if err != nil {
+				return fmt.Errorf("guild member %s: %w", discordID, err)
+			}
+
+			return bot.syncRoles(guild, guildState, guildMember, member, scores)
+		})
+	}
+
+	return jobs, nil
+}
+
+// syncRoles reduces code duplication between SyncRoles and SyncAllRoles
+//
+// The member's managed roles are reconciled with their progress in a single member edit, see DesiredRoles
+func (bot *Bot) syncRoles(guild *discordgo.Guild, guildState *GuildState, guildMember *discordgo.Member, member *Member, scores map[string]int) error {
+	state := guildState.current.Load()
+	registry := state.roles
+	desired := guildState.DesiredRoles(state, member, scores)
+
+	// Keep every role the bot doesn't manage, the spoiler role which members toggle themselves, and trophies
+	roles := make([]string, 0, len(guildMember.Roles)+len(desired))
+	current := make(map[string]bool)
+	kept := make(map[string]bool)
+	for _, roleID := range guildMember.Roles {
+		key, managed := registry.Key(roleID)
+		if managed {
+			current[key] = true
 		}
 
-		// Get the member
-		guildMember, err := bot.session.GuildMember(guild.ID, discord_id)
-		if err != nil {
-			log.Printf("Error (SyncAllRoles): Guild Member %s not found\n", discord_id)
-			return
+		if !managed || key == SpoilerRoleKey || isTrophyRoleKey(key) {
+			roles = append(roles, roleID)
+			if managed {
+				kept[key] = true
+			}
 		}
+	}
 
-		err = bot.syncRoles(guild, guildMember, member, guildState.daily_roles)
-		if err != nil {
-			return
+	changed := false
+	for key := range current {
+		if !kept[key] && !desired[key] {
+			changed = true
 		}
-	})
+	}
 
-	return nil
-}
+	var milestone ManagedRole
+	for key := range desired {
+		if kept[key] {
+			continue
+		}
 
-// syncRoles reduces code duplication between SyncRoles and SyncAllRoles
-func (bot *Bot) syncRoles(guild *discordgo.Guild, guildMember *discordgo.Member, member *Member, daily_roles bool) error {
-	stars := member.Stars
+		roleID, ok := registry.ID(guild, key)
+		if !ok {
+			log.Printf("Error (syncRoles): role %s does not exist\n", key)
+			return ErrDoesNotExist
+		}
+		roles = append(roles, roleID)
 
-	// 10, 20, 30, 40, 50 stars
-	for _, starCount := range []int{4, 8, 12, 16, 20, 24} {
-		role := fmt.Sprintf("%d Stars", starCount)
-		err := bot.AddOrRemoveRole(guild, guildMember, role, stars >= starCount)
-		if err != nil {
-			log.Println("Error (syncRoles) adding/removing role: ", err)
-			return err
+		if current[key] {
+			continue
+		}
+		changed = true
+
+		if role, _ := registry.Get(key); role.Purpose == RoleStars && role.Stars > milestone.Stars {
+			milestone = role
 		}
-		time.Sleep(1 * time.Second)
 	}
 
-	// Connected
-	err := bot.AddRole(guild, guildMember, "Connected")
+	if !changed {
+		return nil
+	}
+
+	log.Printf("Syncing roles for %s\n", guildMember.User.Username)
+	_, err := bot.session.GuildMemberEdit(guild.ID, guildMember.User.ID, &discordgo.GuildMemberParams{Roles: &roles})
 	if err != nil {
-		log.Println("Error (syncRoles) adding role: ", err)
+		log.Println("Error (syncRoles) editing member: ", err)
 		return err
 	}
 
-	// Day 1, 2, 3, ..., 12
-	for day := 1; day <= 12; day++ {
-		role := fmt.Sprintf("Day %02d", day)
-		shouldAdd := len(member.CompletionDayLevel[day]) > 0
-		err := bot.AddOrRemoveRole(guild, guildMember, role, shouldAdd && daily_roles)
-		if err != nil {
-			log.Println("Error (syncRoles) adding/removing role: ", err)
-			return err
-		}
-		time.Sleep(1 * time.Second)
+	// Only promotions are announced, never demotions
+	if milestone.Stars > 0 {
+		bot.announceMilestone(guildState, guildMember, milestone)
 	}
 
 	return nil
 }
 
-// AddOrRemoveRole adds or removes a role from a user
-func (bot *Bot) AddOrRemoveRole(guild *discordgo.Guild, member *discordgo.Member, name string, add bool) error {
+// AddOrRemoveRole adds or removes a managed role from a user
+func (bot *Bot) AddOrRemoveRole(guild *discordgo.Guild, member *discordgo.Member, key string, add bool) error {
 	if add {
-		return bot.AddRole(guild, member, name)
+		return bot.AddRole(guild, member, key)
 	}
 
-	return bot.RemoveRole(guild, member, name)
+	return bot.RemoveRole(guild, member, key)
 }
 
-// ToggleRole toggles a role for a user
-func (bot *Bot) ToggleRole(guild *discordgo.Guild, member *discordgo.Member, name string) (bool, error) {
-	give := !bot.HasRole(guild, member, name)
-	return give, bot.AddOrRemoveRole(guild, member, name, give)
+// ToggleRole toggles a managed role for a user
+func (bot *Bot) ToggleRole(guild *discordgo.Guild, member *discordgo.Member, key string) (bool, error) {
+	give := !bot.HasRole(guild, member, key)
+	return give, bot.AddOrRemoveRole(guild, member, key, give)
 }
 
-// AddRole adds a role to a user
-func (bot *Bot) AddRole(guild *discordgo.Guild, member *discordgo.Member, name string) error {
+// AddRole adds a managed role to a user
+func (bot *Bot) AddRole(guild *discordgo.Guild, member *discordgo.Member, key string) error {
 	// Check if the user already has the role
-	if bot.HasRole(guild, member, name) {
+	if bot.HasRole(guild, member, key) {
 		return nil
 	}
 
-	log.Printf("Adding role %s to %s\n", name, member.User.Username)
-	for _, role := range guild.Roles {
-		if role.Name == name {
-			return bot.session.GuildMemberRoleAdd(guild.ID, member.User.ID, role.ID)
-		}
+	roleID, ok := bot.roleID(guild, key)
+	if !ok {
+		return ErrDoesNotExist
 	}
 
-	return ErrDoesNotExist
+	log.Printf("Adding role %s to %s\n", key, member.User.Username)
+	return bot.session.GuildMemberRoleAdd(guild.ID, member.User.ID, roleID)
 }
 
-// RemoveRole removes a role from a user
-func (bot *Bot) RemoveRole(guild *discordgo.Guild, member *discordgo.Member, name string) error {
+// RemoveRole removes a managed role from a user
+func (bot *Bot) RemoveRole(guild *discordgo.Guild, member *discordgo.Member, key string) error {
 	// Check if the user already doesn't have the role
-	if !bot.HasRole(guild, member, name) {
+	if !bot.HasRole(guild, member, key) {
 		return nil
 	}
 
-	log.Printf("Removing role %s from %s\n", name, member.User.Username)
-	for _, role := range guild.Roles {
-		if role.Name == name {
-			return bot.session.GuildMemberRoleRemove(guild.ID, member.User.ID, role.ID)
-		}
+	roleID, ok := bot.roleID(guild, key)
+	if !ok {
+		return ErrDoesNotExist
 	}
 
-	return ErrDoesNotExist
+	log.Printf("Removing role %s from %s\n", key, member.User.Username)
+	return bot.session.GuildMemberRoleRemove(guild.ID, member.User.ID, roleID)
 }
 
-// HasRole checks if a user has a role
-func (bot *Bot) HasRole(guild *discordgo.Guild, member *discordgo.Member, name string) bool {
+// HasRole checks if a user has a managed role
+func (bot *Bot) HasRole(guild *discordgo.Guild, member *discordgo.Member, key string) bool {
 	// Get the role ID
-	var roleID string
-	for _, role := range guild.Roles {
-		if role.Name == name {
-			roleID = role.ID
-			break
-		}
+	roleID, ok := bot.roleID(guild, key)
+	if !ok {
+		return false
 	}
 
 	// Check if the user has the role
@@ -357,44 +524,38 @@ func (bot *Bot) HasRole(guild *discordgo.Guild, member *discordgo.Member, name s
 	return false
 }
 
-// RemoveAllRoles removes all managed roles from a user
-func (bot *Bot) RemoveAllRoles(guild *discordgo.Guild, member *discordgo.Member) error {
-	managedRoles := []string{
-		"4 Stars", "8 Stars", "12 Stars", "16 Stars", "20 Stars", "24 Stars",
-		"Day 1", "Day 2", "Day 3", "Day 4", "Day 5",
-		"Day 6", "Day 7", "Day 8", "Day 9", "Day 10",
-		"Day 11", "Day 12", "Spoiler", "Connected",
+// roleID looks up a managed role in the guild's registry
+func (bot *Bot) roleID(guild *discordgo.Guild, key string) (string, bool) {
+	guildState, ok := bot.states[guild.ID]
+	if !ok {
+		return "", false
 	}
 
-	for _, roleID := range member.Roles {
-		// Get the name of the role
-		var roleName string
-		for _, role := range guild.Roles {
-			if role.ID == roleID {
-				roleName = role.Name
-				break
-			}
-		}
+	return guildState.current.Load().roles.ID(guild, key)
+}
 
-		// Check if the role is managed
-		managed := false
-		for _, managedRole := range managedRoles {
-			if roleName == managedRole {
-				managed = true
-				break
-			}
-		}
+// RemoveAllRoles removes all managed roles from a user, except for their trophies
+func (bot *Bot) RemoveAllRoles(guild *discordgo.Guild, member *discordgo.Member) error {
+	guildState, ok := bot.states[guild.ID]
+	if !ok {
+		return ErrNotConfigured
+	}
 
-		if managed {
-			err := bot.session.GuildMemberRoleRemove(guild.ID, member.User.ID, roleID)
-			if err != nil {
-				return err
-			}
+	registry := guildState.current.Load().roles
+
+	roles := make([]string, 0, len(member.Roles))
+	for _, roleID := range member.Roles {
+		if key, managed := registry.Key(roleID); !managed || isTrophyRoleKey(key) {
+			roles = append(roles, roleID)
 		}
+	}
 
+	if len(roles) == len(member.Roles) {
+		return nil
 	}
 
-	return nil
+	_, err := bot.session.GuildMemberEdit(guild.ID, member.User.ID, &discordgo.GuildMemberParams{Roles: &roles})
+	return err
 }
 
 // Bit-mask to be considered an admin
@@ -404,3 +565,8 @@ const isAdmin = discordgo.PermissionAdministrator | discordgo.PermissionManageRo
 func (bot *Bot) IsAdmin(member *discordgo.Member) bool {
 	return member.Permissions&isAdmin != 0
 }
+
+// IsOwner checks if a user is one of the bot's owners, rather than an admin of one of its guilds
+func (bot *Bot) IsOwner(userID string) bool {
+	return slices.Contains(bot.config.Owners, userID)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheFile is how a fetched leaderboard is kept on disk
+type cacheFile struct {
+	Fetched     int64           `json:"fetched"`
+	Leaderboard json.RawMessage `json:"leaderboard"`
+}
+
+// cachePath is where a leaderboard is cached on disk
+func (aoc *AdventOfCode) cachePath(key leaderboardKey) string {
+	name := fmt.Sprintf("leaderboard-%s-%s", key.id, key.year)
+	if key.session != "" {
+		name += "-" + key.session
+	}
+
+	return filepath.Join(aoc.cacheDir, name+".json")
+}
+
+// loadCache loads a leaderboard from the disk cache
+//
+// The fetch time is restored as well, so a restart doesn't refetch until the refresh interval has passed
+func (aoc *AdventOfCode) loadCache(key leaderboardKey) *cachedLeaderboard {
+	cached := &cachedLeaderboard{}
+
+	data, err := os.ReadFile(aoc.cachePath(key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("Error reading leaderboard cache: ", err)
+		}
+		return cached
+	}
+
+	var file cacheFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		log.Println("Error parsing leaderboard cache: ", err)
+		return cached
+	}
+
+	leaderboard, err := ParseLeaderboard(bytes.NewReader(file.Leaderboard))
+	if err != nil {
+		log.Println("Error parsing cached leaderboard: ", err)
+		return cached
+	}
+
+	cached.leaderboard = leaderboard
+	cached.fetched = time.Unix(file.Fetched, 0)
+	cached.attempted = cached.fetched
+
+	log.Printf("Loaded cached leaderboard for (%s, %s) from %s\n", key.id, key.year, cached.fetched)
+	return cached
+}
+
+// saveCache writes the raw response for a leaderboard to the disk cache
+func (aoc *AdventOfCode) saveCache(key leaderboardKey, raw []byte, fetched time.Time) error {
+	data, err := json.Marshal(cacheFile{Fetched: fetched.Unix(), Leaderboard: raw})
+	if err != nil {
+		return err
+	}
+
+	// Write then rename, so a crash never leaves a partial cache behind
+	path := aoc.cachePath(key)
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
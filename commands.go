@@ -3,12 +3,26 @@ package main
 import (
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 // RegisterCommands registers the bot's commands with Discord
 func (bot *Bot) RegisterCommands() error {
+	minDay := 1.0
+	minYear := 2015.0
+
+	yearOption := &discordgo.ApplicationCommandOption{
+		Name:        "year",
+		Description: "The event year (defaults to the current one)",
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Required:    false,
+		MinValue:    &minYear,
+	}
+
 	commands := []*discordgo.ApplicationCommand{
 		{
 			Name:        "claim",
@@ -16,10 +30,11 @@ func (bot *Bot) RegisterCommands() error {
 			Type:        discordgo.ChatApplicationCommand,
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Name:        "username",
-					Description: "The username to claim",
-					Type:        discordgo.ApplicationCommandOptionString,
-					Required:    true,
+					Name:         "username",
+					Description:  "The username to claim",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -36,6 +51,26 @@ func (bot *Bot) RegisterCommands() error {
 				},
 			},
 		},
+		{
+			Name:        "link",
+			Description: "Links a member to an Advent of Code account (Admin only)",
+			Type:        discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "member",
+					Description: "The _discord_ user to link",
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Required:    true,
+				},
+				{
+					Name:         "aoc",
+					Description:  "The Advent of Code name or ID to link them to",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
 		{
 			Name:        "stars",
 			Description: "Returns how many stars you have collected (debugging)",
@@ -47,8 +82,51 @@ func (bot *Bot) RegisterCommands() error {
 					Type:        discordgo.ApplicationCommandOptionUser,
 					Required:    false,
 				},
+				yearOption,
 			},
 		},
+		{
+			Name:        "leaderboard",
+			Description: "Shows the private leaderboard for this server",
+			Type:        discordgo.ChatApplicationCommand,
+			Options:     []*discordgo.ApplicationCommandOption{yearOption},
+		},
+		{
+			Name:        "trophies",
+			Description: "Lists the years you have finished",
+			Type:        discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "member",
+					Description: "The _discord_ user to list the trophies of",
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "movers",
+			Description: "Shows whose score grew the most recently",
+			Type:        discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "period",
+					Description: "How far back to look (defaults to daily)",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "daily", Value: "daily"},
+						{Name: "weekly", Value: "weekly"},
+						{Name: "event", Value: "event"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "dms",
+			Description: "Sends you a direct message when you reach a new tier role (toggle)",
+			Type:        discordgo.ChatApplicationCommand,
+		},
 		{
 			Name:        "spoilers",
 			Description: "Gives you access to the spoiler channels (toggle)\n",
@@ -64,9 +142,21 @@ func (bot *Bot) RegisterCommands() error {
 					Description: "The day to set up this channel for",
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Required:    true,
+					MinValue:    &minDay,
+					MaxValue:    25,
 				},
 			},
 		},
+		{
+			Name:        "teardown",
+			Description: "Removes every role and channel permission the bot created (Admin only)",
+			Type:        discordgo.ChatApplicationCommand,
+		},
+		{
+			Name:        "session",
+			Description: "Updates the Advent of Code session cookie (Admin only)",
+			Type:        discordgo.ChatApplicationCommand,
+		},
 		{
 			Name:        "source",
 			Description: "Returns the source code for the bot",
@@ -94,11 +184,41 @@ func (bot *Bot) RegisterCommands() error {
 // AddHandlers adds the bot's discordgo handlers
 func (bot *Bot) AddHandlers() {
 	bot.session.AddHandler(bot.onInteractionCreate)
+	bot.session.AddHandler(bot.onRateLimit)
+}
+
+// onRateLimit logs when Discord rate limits us, discordgo waits out the bucket and retries the request
+func (bot *Bot) onRateLimit(session *discordgo.Session, rateLimit *discordgo.RateLimit) {
+	log.Printf("Rate limited on %s, retrying after %s", rateLimit.URL, rateLimit.RetryAfter)
+	bot.syncPool.RateLimited()
 }
 
 func (bot *Bot) onInteractionCreate(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
 	i := interaction.Interaction
 
+	if interaction.Type == discordgo.InteractionMessageComponent {
+		bot.onComponent(i)
+		return
+	}
+
+	if interaction.Type == discordgo.InteractionModalSubmit {
+		switch interaction.ModalSubmitData().CustomID {
+		case "session":
+			bot.onSessionSubmit(i)
+		}
+		return
+	}
+
+	if interaction.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		switch interaction.ApplicationCommandData().Name {
+		case "claim":
+			bot.onClaimAutocomplete(i, "username")
+		case "link":
+			bot.onClaimAutocomplete(i, "aoc")
+		}
+		return
+	}
+
 	if interaction.Type != discordgo.InteractionApplicationCommand {
 		return
 	}
@@ -108,29 +228,73 @@ func (bot *Bot) onInteractionCreate(session *discordgo.Session, interaction *dis
 		bot.onClaim(i)
 	case "unclaim":
 		bot.onUnclaim(i)
+	case "link":
+		bot.onLink(i)
 	case "stars":
 		bot.onStars(i)
+	case "leaderboard":
+		bot.onLeaderboard(i)
+	case "trophies":
+		bot.onTrophies(i)
+	case "movers":
+		bot.onMovers(i)
+	case "dms":
+		bot.onDirectMessages(i)
 	case "spoilers":
 		bot.onSpoil(i)
 	case "setup":
 		bot.onSetup(i)
+	case "teardown":
+		bot.onTeardown(i)
+	case "session":
+		bot.onSession(i)
 	case "source":
 		log.Printf("Source code requested by @%s", interaction.Member.User.Username)
 		bot.respondToInteraction(i, "https://github.com/Alextopher/aoc-bot", false)
 	case "help":
 		log.Printf("Help requested by @%s", interaction.Member.User.Username)
-		msg := "Help:\n"
-		msg += "- `/claim <username>`: Claims a username by Advent of Code name (or ID)\n"
-		msg += "- `/unclaim`: Removes your claim to an advent of code account\n"
-		msg += "- `/unclaim <member>`: Removes another user's claim to an advent of code account (Admin only)\n"
-		msg += "- `/stars`: Returns how many stars you have collected (debugging)\n"
-		msg += "- `/spoilers`: Gives you access to the spoiler channels (toggle)\n"
-		msg += "- `/source`: links my source code\n"
-		msg += "- `/help`: Shows this help message"
-		bot.respondToInteraction(i, msg, false)
+		bot.respondToInteraction(i, bot.helpMessage(i.GuildID), false)
 	}
 }
 
+// helpMessage builds the help message, which depends on the length of the guild's event and its scoring mode
+func (bot *Bot) helpMessage(guildID string) string {
+	days := 12
+	var scorer Scorer = LocalScorer{}
+	members := 0
+	if guildState, ok := bot.states[guildID]; ok {
+		days = guildState.current.Load().days
+		scorer = guildState.scorer
+		if leaderboard := guildState.cachedLeaderboard(guildState.current.Load().year); leaderboard != nil {
+			members = len(leaderboard.Members)
+		}
+	}
+
+	tiers := scorer.Tiers(days, members)
+
+	msg := "Help:\n"
+	msg += "- `/claim <username>`: Claims a username by Advent of Code name (or ID)\n"
+	msg += "- `/unclaim`: Removes your claim to an advent of code account\n"
+	msg += "- `/unclaim <member>`: Removes another user's claim to an advent of code account (Admin only)\n"
+	msg += "- `/link <member> <aoc>`: Links a member to an Advent of Code name or ID, even one that is already claimed (Admin only)\n"
+	msg += "- `/stars [member] [year]`: Returns how many stars you (or a member) have collected (debugging)\n"
+	msg += "- `/leaderboard [year]`: Shows the private leaderboard for this server\n"
+	msg += "- `/trophies [member]`: Lists the years you (or a member) have finished\n"
+	msg += "- `/movers [period]`: Shows whose score grew the most (daily, weekly or over the whole event)\n"
+	if len(tiers) > 0 {
+		msg += fmt.Sprintf("- `/dms`: Sends you a direct message when you reach a new tier role (from %s up to %s) (toggle)\n", scorer.Format(tiers[0]), scorer.Format(tiers[len(tiers)-1]))
+	} else {
+		msg += "- `/dms`: Sends you a direct message when you reach a new tier role (toggle)\n"
+	}
+	msg += "- `/spoilers`: Gives you access to the spoiler channels (toggle)\n"
+	msg += fmt.Sprintf("- `/setup <day>`: Sets up this channel as the spoiler channel for a day (1-%d) (Admin only)\n", days)
+	msg += "- `/teardown`: Removes every role and channel permission I created, except trophies (Admin only)\n"
+	msg += "- `/session`: Updates the Advent of Code session cookie (Admin only)\n"
+	msg += "- `/source`: links my source code\n"
+	msg += "- `/help`: Shows this help message"
+	return msg
+}
+
 func (bot *Bot) onClaim(interaction *discordgo.Interaction) {
 	deferred := bot.deferInteraction(interaction, true)
 
@@ -145,11 +309,25 @@ func (bot *Bot) onClaim(interaction *discordgo.Interaction) {
 		return
 	}
 
-	// Try to claim the user by name
-	err := guildState.ClaimName(interaction.Member.User.ID, username)
-	if err == ErrDoesNotExist {
-		// If the user doesn't exist, try to claim by ID
-		err = guildState.ClaimID(interaction.Member.User.ID, username)
+	// Guilds that verify claims only claim the user once they prove they own it, see checkVerifications
+	var verification EventVerification
+	var err error
+	if guildState.verifyClaims {
+		verification, err = guildState.StartVerification(interaction.Member.User.ID, username)
+	} else if guildState.claimApproval {
+		// Guilds that approve claims record a request instead, see onClaimDecision
+		var member *Member
+		member, err = guildState.FindClaimable(username)
+		if err == nil {
+			err = bot.requestClaim(guildState, interaction.Member.User.ID, member)
+		}
+	} else {
+		// Try to claim the user by name
+		err = guildState.ClaimName(interaction.Member.User.ID, username)
+		if err == ErrDoesNotExist {
+			// If the user doesn't exist, try to claim by ID
+			err = guildState.ClaimID(interaction.Member.User.ID, username)
+		}
 	}
 
 	if err == ErrDoesNotExist {
@@ -172,7 +350,9 @@ func (bot *Bot) onClaim(interaction *discordgo.Interaction) {
 		// Check if this user just tried to re-claim themselves
 		aocID, ok := guildState.db.GetAdventID(interaction.Member.User.ID)
 		if ok {
-			member, ok := guildState.GetLeaderboard().GetMemberByID(aocID)
+			member, ok := guildState.FindMember(func(leaderboard *Leaderboard) (*Member, bool) {
+				return leaderboard.GetMemberByID(aocID)
+			})
 			if aocID == username || (ok && member.Name == username) {
 				// Report that this user just tried to re-claim themselves
 				deferred.finalize("You have already claimed this user :smile:")
@@ -182,9 +362,20 @@ func (bot *Bot) onClaim(interaction *discordgo.Interaction) {
 
 		// Report that the user has already been claimed
 		deferred.finalize("Error 4: This user has already been claimed, if you believe this is an error, please contact an administrator")
+	} else if err == ErrHasClaim {
+		deferred.finalize("Error 47: You have already claimed an Advent of Code user, run `/unclaim` first to claim another one.")
 	} else if err != nil {
 		// Report that something went wrong
 		deferred.finalize("Error 5: Something went wrong, please try again later.")
+	} else if guildState.verifyClaims {
+		// Report how to finish the claim, there are no roles to sync until then
+		deferred.finalize(fmt.Sprintf("To prove this account is yours, add `%s` to your Advent of Code display name before <t:%d:f>. "+
+			"I check the leaderboard every 15 minutes and will message you once you're verified, then you can change it back.", verification.Token, verification.Expires))
+		return
+	} else if guildState.claimApproval {
+		// Report that the claim is waiting, there are no roles to sync until then
+		deferred.finalize("Success: Your claim has been sent to the admins, I'll message you once they approve it.")
+		return
 	} else {
 		// Report that the user has been claimed
 		deferred.finalize("Success: You have claimed your Advent of Code user!")
@@ -204,6 +395,40 @@ func (bot *Bot) onClaim(interaction *discordgo.Interaction) {
 	}
 }
 
+// onClaimAutocomplete suggests unclaimed Advent of Code members while `/claim` (or `/link`) is being typed
+//
+// Anonymous members are suggested by ID, since they have no name to claim
+func (bot *Bot) onClaimAutocomplete(interaction *discordgo.Interaction, optionName string) {
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+
+	if guildState, ok := bot.states[interaction.GuildID]; ok {
+		input := ""
+		if option := commandOption(interaction, optionName); option != nil {
+			input = option.StringValue()
+		}
+
+		for _, member := range guildState.SuggestClaims(input) {
+			value := member.Name
+			if value == "" {
+				value = fmt.Sprint(member.ID)
+			}
+
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: member.DisplayName(), Value: value})
+		}
+	}
+
+	err := bot.session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+
+	if err != nil {
+		log.Println("onClaimAutocomplete failed while responding to interaction: ", err)
+	}
+}
+
 func (bot *Bot) onUnclaim(interaction *discordgo.Interaction) {
 	// Defer the interaction response
 	deferred := bot.deferInteraction(interaction, true)
@@ -270,14 +495,136 @@ func (bot *Bot) onUnclaim(interaction *discordgo.Interaction) {
 	bot.RemoveAllRoles(guild, member)
 }
 
+// onLink lets an admin link a Discord member to an Advent of Code account, by name or ID
+//
+// Linking takes the account from whoever claimed it and replaces the member's own claim, so either asks for
+// confirmation first, see onLinkConfirm
+func (bot *Bot) onLink(interaction *discordgo.Interaction) {
+	deferred := bot.deferInteraction(interaction, true)
+
+	if !bot.IsAdmin(interaction.Member) {
+		deferred.finalize("Error 42: You must be an admin to link a member.")
+		return
+	}
+
+	user := commandOption(interaction, "member").UserValue(bot.session)
+	username := commandOption(interaction, "aoc").StringValue()
+
+	log.Printf("Link of @%s to %s requested by @%s", user.Username, username, interaction.Member.User.Username)
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalize("Error 43: This guild is not configured, yet.")
+		return
+	}
+
+	member, ok := guildState.FindAccount(username)
+	if !ok {
+		deferred.finalize("Error 44: I couldn't find that Advent of Code user.")
+		return
+	}
+
+	adventID := fmt.Sprint(member.ID)
+
+	// Ask before taking the account from someone else, or replacing the member's own claim
+	var conflicts []string
+	if holder, ok := guildState.db.GetDiscordID(adventID); ok && holder != user.ID {
+		conflicts = append(conflicts, fmt.Sprintf("**%s** has been claimed by <@%s>, they will lose it.", member.DisplayName(), holder))
+	}
+
+	if current, ok := guildState.db.GetAdventID(user.ID); ok && current == adventID {
+		deferred.finalizeMessage(fmt.Sprintf("Success?: <@%s> has already claimed **%s**.", user.ID, member.DisplayName()), nil)
+		return
+	} else if ok {
+		conflicts = append(conflicts, fmt.Sprintf("<@%s> has claimed Advent of Code ID %s, it will be replaced.", user.ID, current))
+	}
+
+	if len(conflicts) == 0 {
+		bot.finishLink(&deferred, interaction, user.ID, member)
+		return
+	}
+
+	arg := "confirm:" + user.ID + ":" + adventID
+	deferred.finalizeMessage(strings.Join(conflicts, "\n")+"\nLink them anyway?", []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Link", Style: discordgo.DangerButton, CustomID: "link:" + arg},
+				discordgo.Button{Label: "Cancel", Style: discordgo.SecondaryButton, CustomID: "link:cancel"},
+			},
+		},
+	})
+}
+
+// onLinkConfirm handles an admin pressing Link or Cancel on a `/link` that needed confirmation
+//
+// Buttons are "link:confirm:<discord id>:<advent id>" or "link:cancel"
+func (bot *Bot) onLinkConfirm(interaction *discordgo.Interaction, arg string) {
+	deferred := bot.deferUpdate(interaction)
+
+	action, rest, _ := strings.Cut(arg, ":")
+	if action != "confirm" {
+		deferred.finalizeMessage("Link cancelled.", nil)
+		return
+	}
+
+	if !bot.IsAdmin(interaction.Member) {
+		deferred.finalizeMessage("Error 42: You must be an admin to link a member.", nil)
+		return
+	}
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalizeMessage("Error 43: This guild is not configured, yet.", nil)
+		return
+	}
+
+	discordID, adventID, _ := strings.Cut(rest, ":")
+	member, ok := guildState.FindMember(func(leaderboard *Leaderboard) (*Member, bool) {
+		return leaderboard.GetMemberByID(adventID)
+	})
+	if !ok {
+		deferred.finalizeMessage("Error 44: I couldn't find that Advent of Code user.", nil)
+		return
+	}
+
+	log.Printf("Link of %s to %s confirmed by @%s", discordID, adventID, interaction.Member.User.Username)
+	bot.finishLink(&deferred, interaction, discordID, member)
+}
+
+// finishLink links a member once `/link` has been confirmed (if it needed to be), and reports back to the admin
+func (bot *Bot) finishLink(deferred *DeferredInteraction, interaction *discordgo.Interaction, discordID string, member *Member) {
+	guild, err := bot.session.State.Guild(interaction.GuildID)
+	if err != nil {
+		log.Println("Error (finishLink) getting guild: ", err)
+		deferred.finalizeMessage("Error 45: Something went wrong, please try again later.", nil)
+		return
+	}
+
+	guildMember, err := bot.session.GuildMember(guild.ID, discordID)
+	if err != nil {
+		log.Println("Error (finishLink) getting guild member: ", err)
+		deferred.finalizeMessage("Error 45: Something went wrong, please try again later.", nil)
+		return
+	}
+
+	err = bot.Link(guild, guildMember, fmt.Sprint(member.ID), interaction.Member.User.ID)
+	if err != nil {
+		log.Println("Error (finishLink) linking: ", err)
+		deferred.finalizeMessage("Error 46: Something went wrong, please try again later.", nil)
+		return
+	}
+
+	deferred.finalizeMessage(fmt.Sprintf("Success: <@%s> has been linked to **%s**!", discordID, member.DisplayName()), nil)
+}
+
 func (bot *Bot) onStars(interaction *discordgo.Interaction) {
 	// Defer the interaction response
 	deferred := bot.deferInteraction(interaction, true)
 
 	user := interaction.Member.User
 	self := true
-	if len(interaction.ApplicationCommandData().Options) > 0 {
-		user = interaction.ApplicationCommandData().Options[0].UserValue(bot.session)
+	if option := commandOption(interaction, "member"); option != nil {
+		user = option.UserValue(bot.session)
 		self = false
 	}
 
@@ -289,7 +636,11 @@ func (bot *Bot) onStars(interaction *discordgo.Interaction) {
 		return
 	}
 
-	guildState.UpdateLeaderboard()
+	year, ok := commandYear(interaction, guildState)
+	if !ok {
+		deferred.finalize(fmt.Sprintf("Error 34: This server doesn't follow Advent of Code %s, it follows %s.", year, strings.Join(guildState.current.Load().years, ", ")))
+		return
+	}
 
 	id, ok := guildState.db.GetAdventID(user.ID)
 	if !ok {
@@ -297,8 +648,17 @@ func (bot *Bot) onStars(interaction *discordgo.Interaction) {
 		return
 	}
 
-	aocMember, ok := guildState.GetLeaderboard().GetMemberByID(id)
-	if !ok {
+	leaderboard := guildState.GetYearLeaderboard(year)
+	if leaderboard == nil {
+		deferred.finalize("Error 19: The leaderboard isn't available right now, please try again later.")
+		return
+	}
+
+	aocMember, ok := leaderboard.GetMemberByID(id)
+	if !ok && year != guildState.current.Load().year {
+		deferred.finalize(fmt.Sprintf("Error 35: That account isn't on the Advent of Code %s leaderboard.", year))
+		return
+	} else if !ok {
 		deferred.finalize("Error 11: Something odd happened here, did you quit the leaderboard?")
 		return
 	}
@@ -311,11 +671,16 @@ func (bot *Bot) onStars(interaction *discordgo.Interaction) {
 	}
 
 	// Success!
+	suffix := ""
+	if year != guildState.current.Load().year {
+		suffix = " in " + year
+	}
+
 	if self {
-		msg := fmt.Sprintf("You have collected **%d** stars!", aocMember.Stars)
+		msg := fmt.Sprintf("You have collected **%d** stars%s!", aocMember.Stars, suffix)
 		deferred.finalize(msg)
 	} else {
-		msg := fmt.Sprintf("They have collected **%d** stars!", aocMember.Stars)
+		msg := fmt.Sprintf("They have collected **%d** stars%s!", aocMember.Stars, suffix)
 		deferred.finalize(msg)
 	}
 
@@ -333,6 +698,244 @@ func (bot *Bot) onStars(interaction *discordgo.Interaction) {
 	}
 }
 
+// leaderboardPageSize is the number of members shown on each page of `/leaderboard`
+const leaderboardPageSize = 20
+
+func (bot *Bot) onLeaderboard(interaction *discordgo.Interaction) {
+	// Defer the interaction response
+	deferred := bot.deferInteraction(interaction, false)
+
+	log.Printf("Leaderboard requested by @%s", interaction.Member.User.Username)
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalize("Error 18: This guild is not configured, yet.")
+		return
+	}
+
+	year, ok := commandYear(interaction, guildState)
+	if !ok {
+		deferred.finalize(fmt.Sprintf("Error 34: This server doesn't follow Advent of Code %s, it follows %s.", year, strings.Join(guildState.current.Load().years, ", ")))
+		return
+	}
+
+	content, components := bot.renderLeaderboard(guildState, year, 0)
+	deferred.finalizeMessage(content, components)
+}
+
+// onComponent routes message component (button) interactions by their custom id prefix
+func (bot *Bot) onComponent(interaction *discordgo.Interaction) {
+	customID := interaction.MessageComponentData().CustomID
+	prefix, arg, _ := strings.Cut(customID, ":")
+
+	switch prefix {
+	case "leaderboard":
+		bot.onLeaderboardPage(interaction, arg)
+	case "teardown":
+		bot.onTeardownConfirm(interaction, arg)
+	case "link":
+		bot.onLinkConfirm(interaction, arg)
+	case "approve", "reject":
+		bot.onClaimDecision(interaction, prefix == "approve", arg)
+	}
+}
+
+func (bot *Bot) onLeaderboardPage(interaction *discordgo.Interaction, arg string) {
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		return
+	}
+
+	// Buttons are "leaderboard:<year>:<page>", or "leaderboard:<page>" for the current year
+	year, pageArg, ok := strings.Cut(arg, ":")
+	if !ok {
+		year, pageArg = guildState.current.Load().year, arg
+	}
+
+	page, err := strconv.Atoi(pageArg)
+	if err != nil {
+		log.Println("Error (onLeaderboardPage) parsing page: ", err)
+		return
+	}
+
+	if !guildState.FollowsYear(year) {
+		return
+	}
+
+	content, components := bot.renderLeaderboard(guildState, year, page)
+	err = bot.session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Components:      components,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+
+	if err != nil {
+		log.Println("onLeaderboardPage failed while responding to interaction: ", err)
+	}
+}
+
+// renderLeaderboard renders a single page of a year's leaderboard along with its navigation buttons
+func (bot *Bot) renderLeaderboard(guildState *GuildState, year string, page int) (string, []discordgo.MessageComponent) {
+	leaderboard := guildState.GetYearLeaderboard(year)
+	if leaderboard == nil {
+		return "Error 19: The leaderboard isn't available right now, please try again later.", nil
+	}
+
+	rankings := leaderboard.Rank(guildState.scorer)
+	if len(rankings) == 0 {
+		return "The leaderboard is empty.", nil
+	}
+
+	pages := (len(rankings) + leaderboardPageSize - 1) / leaderboardPageSize
+	page = max(0, min(page, pages-1))
+
+	discordIDs := guildState.db.GetDiscordIDs()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Advent of Code %s Leaderboard** (page %d/%d)\n", year, page+1, pages)
+	if fetched, stale := guildState.LeaderboardStatus(year); stale {
+		fmt.Fprintf(&sb, ":warning: Advent of Code can't be reached right now, this is from <t:%d:R>\n", fetched.Unix())
+	}
+
+	start := page * leaderboardPageSize
+	end := min(start+leaderboardPageSize, len(rankings))
+	for rank, ranking := range rankings[start:end] {
+		member := ranking.Member
+		fmt.Fprintf(&sb, "%d. %s: %s, %d :star:\n", start+rank+1, mention(member, discordIDs), guildState.scorer.Format(ranking.Score), member.Stars)
+	}
+
+	if pages == 1 {
+		return sb.String(), nil
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("leaderboard:%s:%d", year, page-1),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("leaderboard:%s:%d", year, page+1),
+					Disabled: page == pages-1,
+				},
+			},
+		},
+	}
+
+	return sb.String(), components
+}
+
+func (bot *Bot) onDirectMessages(interaction *discordgo.Interaction) {
+	deferred := bot.deferInteraction(interaction, true)
+
+	log.Printf("Toggling direct messages has been requested by @%s", interaction.Member.User.Username)
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalize("Error 20: This guild is not configured, yet.")
+		return
+	}
+
+	enabled := !guildState.db.WantsDirectMessages(interaction.Member.User.ID)
+	err := guildState.db.SetDirectMessages(interaction.Member.User.ID, enabled)
+	if err != nil {
+		log.Println("Error (onDirectMessages) saving preference: ", err)
+		deferred.finalize("Error 21: Something went wrong, please try again later.")
+	} else if enabled {
+		deferred.finalize("Success: I will send you a direct message when you reach a new tier role!")
+	} else {
+		deferred.finalize("Success: I will no longer send you direct messages.")
+	}
+}
+
+// commandOption gets a command option by name, nil if it wasn't given
+func commandOption(interaction *discordgo.Interaction, name string) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, option := range interaction.ApplicationCommandData().Options {
+		if option.Name == name {
+			return option
+		}
+	}
+
+	return nil
+}
+
+// commandYear gets the year a command asked for, the guild's current year by default, and whether the guild follows it
+func commandYear(interaction *discordgo.Interaction, guildState *GuildState) (string, bool) {
+	year := guildState.current.Load().year
+	if option := commandOption(interaction, "year"); option != nil {
+		year = fmt.Sprint(option.IntValue())
+	}
+
+	return year, guildState.FollowsYear(year)
+}
+
+// mention renders a claimed member as a Discord mention, and anyone else by their Advent of Code name
+func mention(member *Member, discordIDs map[string]string) string {
+	if discordID, ok := discordIDs[fmt.Sprint(member.ID)]; ok {
+		return fmt.Sprintf("<@%s>", discordID)
+	}
+	return member.DisplayName()
+}
+
+func (bot *Bot) onTrophies(interaction *discordgo.Interaction) {
+	deferred := bot.deferInteraction(interaction, false)
+
+	user := interaction.Member.User
+	if option := commandOption(interaction, "member"); option != nil {
+		user = option.UserValue(bot.session)
+	}
+
+	log.Printf("Trophies of @%s requested by @%s", user.Username, interaction.Member.User.Username)
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalize("Error 36: This guild is not configured, yet.")
+		return
+	}
+
+	content, err := guildState.renderTrophies(user.ID)
+	if err != nil {
+		deferred.finalize("Error 37: That user hasn't ran `/claim` yet.")
+		return
+	}
+
+	deferred.finalizeMessage(content, nil)
+}
+
+func (bot *Bot) onMovers(interaction *discordgo.Interaction) {
+	deferred := bot.deferInteraction(interaction, false)
+
+	period := "daily"
+	if len(interaction.ApplicationCommandData().Options) > 0 {
+		period = interaction.ApplicationCommandData().Options[0].StringValue()
+	}
+
+	log.Printf("Movers (%s) requested by @%s", period, interaction.Member.User.Username)
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalize("Error 22: This guild is not configured, yet.")
+		return
+	}
+
+	content, err := guildState.renderMovers(period, time.Now())
+	if err != nil {
+		log.Println("Error (onMovers) rendering movers: ", err)
+		deferred.finalize("Error 23: Something went wrong, please try again later.")
+		return
+	}
+
+	deferred.finalizeMessage(content, nil)
+}
+
 func (bot *Bot) onSpoil(interaction *discordgo.Interaction) {
 	deferred := bot.deferInteraction(interaction, true)
 
@@ -345,7 +948,7 @@ func (bot *Bot) onSpoil(interaction *discordgo.Interaction) {
 		return
 	}
 
-	added, err := bot.ToggleRole(guild, interaction.Member, "Spoiler")
+	added, err := bot.ToggleRole(guild, interaction.Member, SpoilerRoleKey)
 	if err != nil {
 		log.Println("Error (onStars) toggling role: ", err)
 		deferred.finalize("Error 14: Something went wrong, please try again later.")
@@ -370,6 +973,18 @@ func (bot *Bot) onSetup(interaction *discordgo.Interaction) {
 
 	day := interaction.ApplicationCommandData().Options[0].IntValue()
 
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalize("Error 24: This guild is not configured, yet.")
+		return
+	}
+
+	days := guildState.current.Load().days
+	if day < 1 || day > int64(days) {
+		deferred.finalize(fmt.Sprintf("Error 25: This event only has days 1 to %d.", days))
+		return
+	}
+
 	guild, err := bot.session.Guild(interaction.GuildID)
 	if err != nil {
 		log.Println("Error (onSetup) getting guild: ", err)
@@ -386,6 +1001,67 @@ func (bot *Bot) onSetup(interaction *discordgo.Interaction) {
 	}
 }
 
+func (bot *Bot) onTeardown(interaction *discordgo.Interaction) {
+	log.Printf("Teardown requested by @%s", interaction.Member.User.Username)
+
+	if !bot.IsAdmin(interaction.Member) {
+		bot.respondToInteraction(interaction, "Error 26: You must be an admin to tear down the bot.", true)
+		return
+	}
+
+	err := bot.session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "This will remove every role I created from every member, delete those roles, and revert the spoiler channels. Are you sure?",
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{Label: "Tear down", Style: discordgo.DangerButton, CustomID: "teardown:confirm"},
+						discordgo.Button{Label: "Cancel", Style: discordgo.SecondaryButton, CustomID: "teardown:cancel"},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		log.Println("onTeardown failed while responding to interaction: ", err)
+	}
+}
+
+func (bot *Bot) onTeardownConfirm(interaction *discordgo.Interaction, arg string) {
+	deferred := bot.deferUpdate(interaction)
+
+	if arg != "confirm" {
+		deferred.finalizeMessage("Teardown cancelled.", nil)
+		return
+	}
+
+	if !bot.IsAdmin(interaction.Member) {
+		deferred.finalizeMessage("Error 26: You must be an admin to tear down the bot.", nil)
+		return
+	}
+
+	log.Printf("Teardown confirmed by @%s", interaction.Member.User.Username)
+
+	guild, err := bot.session.State.Guild(interaction.GuildID)
+	if err != nil {
+		log.Println("Error (onTeardownConfirm) getting guild: ", err)
+		deferred.finalizeMessage("Error 27: Something went wrong, please try again later.", nil)
+		return
+	}
+
+	report, err := bot.Teardown(guild)
+	if err != nil {
+		log.Println("Error (onTeardownConfirm) tearing down: ", err)
+		deferred.finalizeMessage(fmt.Sprintf("Error 28: Teardown stopped part way. %s", report), nil)
+		return
+	}
+
+	deferred.finalizeMessage(fmt.Sprintf("Success: %s Roles will be created again the next time I start, unless this server is removed from my config.", report), nil)
+}
+
 // DeferredInteraction is a small wrapper around an interaction that allows for deferring the response
 type DeferredInteraction struct {
 	interaction *discordgo.Interaction
@@ -415,6 +1091,22 @@ func (bot *Bot) deferInteraction(i *discordgo.Interaction, isEphemeral bool) Def
 	}
 }
 
+// deferUpdate acknowledges a component interaction, the message it came from is edited when finalized
+func (bot *Bot) deferUpdate(i *discordgo.Interaction) DeferredInteraction {
+	err := bot.session.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	if err != nil {
+		log.Println("deferUpdate failed while responding to interaction: ", err)
+	}
+
+	return DeferredInteraction{
+		interaction: i,
+		bot:         bot,
+	}
+}
+
 func (di *DeferredInteraction) finalize(content string) {
 	_, err := di.bot.session.InteractionResponseEdit(di.interaction, &discordgo.WebhookEdit{
 		Content: &content,
@@ -425,6 +1117,37 @@ func (di *DeferredInteraction) finalize(content string) {
 	}
 }
 
+// finalizeMessage is finalize for responses that carry components
+//
+// Mentions are rendered but never ping anyone
+func (di *DeferredInteraction) finalizeMessage(content string, components []discordgo.MessageComponent) {
+	if components == nil {
+		components = []discordgo.MessageComponent{}
+	}
+
+	_, err := di.bot.session.InteractionResponseEdit(di.interaction, &discordgo.WebhookEdit{
+		Content:         &content,
+		Components:      &components,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+
+	if err != nil {
+		log.Println("finalizeMessage failed while responding to interaction: ", err)
+	}
+}
+
+// followup sends an ephemeral message to whoever triggered a deferred interaction, leaving the response alone
+func (di *DeferredInteraction) followup(content string) {
+	_, err := di.bot.session.FollowupMessageCreate(di.interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+
+	if err != nil {
+		log.Println("followup failed while responding to interaction: ", err)
+	}
+}
+
 // respondToInteraction responds to a new interaction that hasn't been deferred
 func (bot *Bot) respondToInteraction(i *discordgo.Interaction, content string, isEphemeral bool) {
 	flags := discordgo.MessageFlags(0)
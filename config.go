@@ -7,10 +7,32 @@ import (
 
 // GuildConfig is the config per guild
 type GuildConfig struct {
-	Year          string `json:"year"`
+	// Year is the event year, or "auto" to follow the calendar and move on to each new event as it starts
+	Year string `json:"year"`
+	// PastYears are earlier events that /stars and /leaderboard can still show, roles always follow Year
+	PastYears []string `json:"past_years,omitempty"`
+	// Mode selects how members are ranked and earn tier roles: "local" (default), "stars", "time-to-solve" or
+	// "part2-delta"
 	Mode          string `json:"mode"`
 	LeaderboardID string `json:"leaderboard_id"`
-	DailyRoles    bool   `json:"daily_roles"`
+	// LeaderboardIDs lists more private leaderboards to merge with LeaderboardID, for clubs that outgrow one
+	LeaderboardIDs []string `json:"leaderboard_ids,omitempty"`
+	DailyRoles     bool     `json:"daily_roles"`
+	// AnnounceChannelID is the channel that star announcements are posted to, empty disables announcements
+	AnnounceChannelID string `json:"announce_channel_id"`
+	// MoversReport schedules a "daily" or "weekly" biggest movers report in the announcement channel
+	MoversReport string `json:"movers_report"`
+	// Days overrides the number of days in the current year's event, by default it is derived from the year
+	Days int `json:"days"`
+	// AdminChannelID is where the bot reports problems that need an admin, such as an expired session cookie
+	AdminChannelID string `json:"admin_channel_id"`
+	// VerifyClaims makes members prove they own an account, by putting a token in their display name, before it is claimed
+	VerifyClaims bool `json:"verify_claims"`
+	// ClaimApproval makes claims wait for an admin to approve them in the admin channel
+	ClaimApproval bool `json:"claim_approval"`
+	// SessionCookie overrides the global session cookie, for leaderboards that only this guild's owner can read. It is set
+	// when an admin of the guild who isn't one of the bot's owners uses `/session`.
+	SessionCookie string `json:"session_cookie,omitempty"`
 }
 
 // Config is the bot config
@@ -21,6 +43,16 @@ type Config struct {
 	// The Advent of Code session cookie
 	SessionCookie string `json:"session_cookie"`
 
+	// The Advent of Code base URL, defaults to https://adventofcode.com/ (see `aocbot fake-aoc`)
+	AdventOfCodeURL string `json:"aoc_url"`
+
+	// The number of members whose roles are synced at once, across every guild (default 4)
+	SyncConcurrency int `json:"sync_concurrency"`
+
+	// Discord ids of the bot's owners, they are sent a direct message when a guild without an admin channel needs attention.
+	// Only they may replace the global session cookie.
+	Owners []string `json:"owners"`
+
 	// Map guild ids to (year, leaderboard id) pairs
 	Guilds map[string]GuildConfig `json:"guilds"`
 }
@@ -35,3 +67,10 @@ func ParseConfig(reader io.Reader) (*Config, error) {
 
 	return &config, nil
 }
+
+// Save writes the config, in the same format ParseConfig reads
+func (config *Config) Save(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config)
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"io"
+	"maps"
+	"slices"
 	"sync"
 )
 
@@ -11,12 +13,26 @@ type DatabaseEvent struct {
 	Create   *EventCreate   `json:"create,omitempty"`
 	Delete   *EventDelete   `json:"delete,omitempty"`
 	Snapshot *EventSnapshot `json:"snapshot,omitempty"`
+
+	Preference *EventPreference `json:"preference,omitempty"`
+	Role       *EventRole       `json:"role,omitempty"`
+	Channel    *EventChannel    `json:"channel,omitempty"`
+	Season     *EventSeason     `json:"season,omitempty"`
+
+	Verification *EventVerification `json:"verification,omitempty"`
+
+	ClaimRequest *EventClaimRequest  `json:"claim_request,omitempty"`
+	ClaimApprove *EventClaimDecision `json:"claim_approve,omitempty"`
+	ClaimReject  *EventClaimDecision `json:"claim_reject,omitempty"`
 }
 
 // EventCreate is a database event for creating a claim
+//
+// AdminID is set when an admin linked the accounts rather than the user claiming it themselves
 type EventCreate struct {
 	DiscordID string `json:"discord_id"`
 	AdventID  string `json:"aoc_id"`
+	AdminID   string `json:"admin_id,omitempty"`
 }
 
 // NewEventCreate creates a new database event for creating a claim
@@ -59,11 +75,152 @@ func NewEventSnapshot(timestamp int64, scores map[string]int) *DatabaseEvent {
 	}
 }
 
+// EventPreference is a database event for changing a user's notification preferences
+type EventPreference struct {
+	DiscordID      string `json:"discord_id"`
+	DirectMessages bool   `json:"direct_messages"`
+}
+
+// NewEventPreference creates a new database event for changing a user's notification preferences
+func NewEventPreference(discordID string, directMessages bool) *DatabaseEvent {
+	return &DatabaseEvent{
+		Preference: &EventPreference{
+			DiscordID:      discordID,
+			DirectMessages: directMessages,
+		},
+	}
+}
+
+// EventRole is a database event for recording the Discord id of a managed role
+type EventRole struct {
+	Key    string `json:"key"`
+	RoleID string `json:"role_id"`
+}
+
+// NewEventRole creates a new database event for recording the Discord id of a managed role
+func NewEventRole(key, roleID string) *DatabaseEvent {
+	return &DatabaseEvent{
+		Role: &EventRole{
+			Key:    key,
+			RoleID: roleID,
+		},
+	}
+}
+
+// EventChannel is a database event for setting up (or reverting) a spoiler channel
+//
+// The @everyone overwrite from before the channel was set up is kept so that it can be restored. EveryoneUnknown is set
+// for channels that were set up before channels were recorded, whose original overwrite is lost.
+type EventChannel struct {
+	ChannelID string `json:"channel_id"`
+	Day       int    `json:"day"`
+	Removed   bool   `json:"removed,omitempty"`
+
+	EveryoneUnknown bool `json:"everyone_unknown,omitempty"`
+
+	HadEveryone   bool  `json:"had_everyone,omitempty"`
+	EveryoneAllow int64 `json:"everyone_allow,omitempty"`
+	EveryoneDeny  int64 `json:"everyone_deny,omitempty"`
+}
+
+// NewEventChannel creates a new database event for setting up (or reverting) a spoiler channel
+func NewEventChannel(channel EventChannel) *DatabaseEvent {
+	return &DatabaseEvent{
+		Channel: &channel,
+	}
+}
+
+// EventSeason is a database event for moving on to a new event year
+//
+// Snapshots from before a season started stay in the log, but no longer count towards anything. The managed roles each
+// claimed member held at the end of the previous year are archived with it.
+type EventSeason struct {
+	Year      string              `json:"year"`
+	Previous  string              `json:"previous"`
+	Timestamp int64               `json:"timestamp"`
+	Roles     map[string][]string `json:"roles,omitempty"`
+}
+
+// NewEventSeason creates a new database event for moving on to a new event year
+func NewEventSeason(season EventSeason) *DatabaseEvent {
+	return &DatabaseEvent{
+		Season: &season,
+	}
+}
+
+// The outcomes of a verification, see EventVerification
+const (
+	VerificationVerified = "verified"
+	VerificationExpired  = "expired"
+	VerificationReplaced = "replaced"
+	VerificationTaken    = "taken"
+)
+
+// EventVerification is a database event for starting (or finishing) an ownership verification of a claim
+//
+// Outcome is empty while the verification is pending
+type EventVerification struct {
+	DiscordID string `json:"discord_id"`
+	AdventID  string `json:"aoc_id"`
+	Token     string `json:"token"`
+	Expires   int64  `json:"expires"`
+	Outcome   string `json:"outcome,omitempty"`
+}
+
+// NewEventVerification creates a new database event for starting (or finishing) an ownership verification of a claim
+func NewEventVerification(verification EventVerification) *DatabaseEvent {
+	return &DatabaseEvent{
+		Verification: &verification,
+	}
+}
+
+// EventClaimRequest is a database event for a claim that is waiting for an admin's approval
+type EventClaimRequest struct {
+	DiscordID string `json:"discord_id"`
+	AdventID  string `json:"aoc_id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// NewEventClaimRequest creates a new database event for a claim that is waiting for an admin's approval
+func NewEventClaimRequest(discordID, adventID string, timestamp int64) *DatabaseEvent {
+	return &DatabaseEvent{
+		ClaimRequest: &EventClaimRequest{
+			DiscordID: discordID,
+			AdventID:  adventID,
+			Timestamp: timestamp,
+		},
+	}
+}
+
+// EventClaimDecision is a database event for an admin approving or rejecting a claim request
+//
+// An approval is followed by the EventCreate of the claim itself. A rejection without an admin is a request that was
+// withdrawn because it never reached the admins, see Database.WithdrawClaim
+type EventClaimDecision struct {
+	DiscordID string `json:"discord_id"`
+	AdventID  string `json:"aoc_id"`
+	AdminID   string `json:"admin_id"`
+}
+
+// NewEventClaimDecision creates a new database event for an admin approving or rejecting a claim request
+func NewEventClaimDecision(decision EventClaimDecision, approved bool) *DatabaseEvent {
+	if approved {
+		return &DatabaseEvent{ClaimApprove: &decision}
+	}
+	return &DatabaseEvent{ClaimReject: &decision}
+}
+
 // Database keeps an append-only log file of bot operation
 //
 // - tracks APOD id claims
 // - tracks APOD id unclaims
 // - creates APOD total score snapshots
+// - tracks notification preferences
+// - tracks the ids of managed roles
+// - tracks spoiler channels
+// - tracks event years, for guilds that follow the calendar
+// - tracks ownership verifications of claims
+// - tracks claims waiting for approval
 type Database struct {
 	sync.RWMutex
 
@@ -73,8 +230,26 @@ type Database struct {
 	// In-memory, per guild, mapping of discord ids to Advent of Code ids
 	mappings map[string]string
 
-	// Timestamped snapshot of total scores
-	scores map[string]int
+	// Every snapshot of total scores, oldest first
+	snapshots []EventSnapshot
+
+	// Discord ids of users that want direct messages
+	directMessages map[string]bool
+
+	// Managed role keys to Discord role ids
+	roles map[string]string
+
+	// Spoiler channels by channel id
+	channels map[string]EventChannel
+
+	// Every season that has started, oldest first
+	seasons []EventSeason
+
+	// Pending verifications by Discord id
+	verifications map[string]EventVerification
+
+	// Claims waiting for approval by Discord id
+	claimRequests map[string]EventClaimRequest
 }
 
 // NewDatabase creates a new database
@@ -83,7 +258,12 @@ func NewDatabase(reader io.Reader, writer io.Writer) (*Database, error) {
 	database := &Database{
 		writer:   json.NewEncoder(writer),
 		mappings: make(map[string]string),
-		scores:   make(map[string]int),
+
+		directMessages: make(map[string]bool),
+		roles:          make(map[string]string),
+		channels:       make(map[string]EventChannel),
+		verifications:  make(map[string]EventVerification),
+		claimRequests:  make(map[string]EventClaimRequest),
 	}
 
 	decoder := json.NewDecoder(reader)
@@ -105,6 +285,37 @@ func NewDatabase(reader io.Reader, writer io.Writer) (*Database, error) {
 			database.mappings[event.Create.DiscordID] = event.Create.AdventID
 		case event.Delete != nil:
 			delete(database.mappings, event.Delete.DiscordID)
+		case event.Snapshot != nil:
+			database.snapshots = append(database.snapshots, *event.Snapshot)
+		case event.Preference != nil:
+			database.directMessages[event.Preference.DiscordID] = event.Preference.DirectMessages
+		case event.Role != nil:
+			if event.Role.RoleID == "" {
+				delete(database.roles, event.Role.Key)
+			} else {
+				database.roles[event.Role.Key] = event.Role.RoleID
+			}
+		case event.Channel != nil:
+			if event.Channel.Removed {
+				delete(database.channels, event.Channel.ChannelID)
+			} else {
+				database.channels[event.Channel.ChannelID] = *event.Channel
+			}
+		case event.Season != nil:
+			database.seasons = append(database.seasons, *event.Season)
+			database.snapshots = nil
+		case event.Verification != nil:
+			if event.Verification.Outcome != "" {
+				delete(database.verifications, event.Verification.DiscordID)
+			} else {
+				database.verifications[event.Verification.DiscordID] = *event.Verification
+			}
+		case event.ClaimRequest != nil:
+			database.claimRequests[event.ClaimRequest.DiscordID] = *event.ClaimRequest
+		case event.ClaimApprove != nil:
+			delete(database.claimRequests, event.ClaimApprove.DiscordID)
+		case event.ClaimReject != nil:
+			delete(database.claimRequests, event.ClaimReject.DiscordID)
 		}
 	}
 
@@ -144,6 +355,61 @@ func (database *Database) Unclaim(discordID string) error {
 	return err
 }
 
+// Link claims an Advent of Code user for a discord user on behalf of an admin
+//
+// Any existing claim of the Advent of Code user is removed first, and the discord user's previous claim is replaced. The
+// discord user's pending verification and claim request are dropped, the admin settled those. The discord id of the
+// user who lost the Advent of Code user (if any) is returned.
+func (database *Database) Link(discordID, adventID, adminID string) (string, error) {
+	database.Lock()
+	defer database.Unlock()
+
+	if verification, ok := database.verifications[discordID]; ok {
+		delete(database.verifications, discordID)
+
+		verification.Outcome = VerificationReplaced
+		err := database.writer.Encode(NewEventVerification(verification))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if request, ok := database.claimRequests[discordID]; ok {
+		delete(database.claimRequests, discordID)
+
+		decision := EventClaimDecision{DiscordID: discordID, AdventID: request.AdventID, AdminID: adminID}
+		err := database.writer.Encode(NewEventClaimDecision(decision, false))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	previous := ""
+	for id, claimed := range database.mappings {
+		if claimed == adventID && id != discordID {
+			previous = id
+		}
+	}
+
+	if previous != "" {
+		delete(database.mappings, previous)
+
+		// Write the event to the database
+		err := database.writer.Encode(NewEventDelete(previous))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	database.mappings[discordID] = adventID
+
+	event := NewEventCreate(discordID, adventID)
+	event.Create.AdminID = adminID
+
+	// Write the event to the database
+	return previous, database.writer.Encode(event)
+}
+
 // GetAdventID gets the Advent of Code ID for a discord user
 func (database *Database) GetAdventID(discordID string) (string, bool) {
 	database.RLock()
@@ -171,6 +437,20 @@ func (database *Database) GetDiscordID(adventID string) (string, bool) {
 	return "", false
 }
 
+// GetDiscordIDs gets a copy of every claim, keyed by Advent of Code id
+func (database *Database) GetDiscordIDs() map[string]string {
+	database.RLock()
+
+	// Invert the mappings
+	ids := make(map[string]string, len(database.mappings))
+	for discordID, adventID := range database.mappings {
+		ids[adventID] = discordID
+	}
+
+	database.RUnlock()
+	return ids
+}
+
 // CheckClaim checks if an Advent of Code user has been claimed
 func (database *Database) CheckClaim(adventID string) bool {
 	database.RLock()
@@ -187,12 +467,99 @@ func (database *Database) CheckClaim(adventID string) bool {
 	return false
 }
 
+// SetDirectMessages sets whether a discord user wants to receive direct messages
+func (database *Database) SetDirectMessages(discordID string, enabled bool) error {
+	database.Lock()
+
+	database.directMessages[discordID] = enabled
+
+	// Write the event to the database
+	err := database.writer.Encode(NewEventPreference(discordID, enabled))
+
+	database.Unlock()
+	return err
+}
+
+// WantsDirectMessages checks if a discord user wants to receive direct messages
+func (database *Database) WantsDirectMessages(discordID string) bool {
+	database.RLock()
+	enabled := database.directMessages[discordID]
+	database.RUnlock()
+	return enabled
+}
+
+// SetRoleID records the Discord id of a managed role, an empty id forgets the role
+func (database *Database) SetRoleID(key, roleID string) error {
+	database.Lock()
+
+	if roleID == "" {
+		delete(database.roles, key)
+	} else {
+		database.roles[key] = roleID
+	}
+
+	// Write the event to the database
+	err := database.writer.Encode(NewEventRole(key, roleID))
+
+	database.Unlock()
+	return err
+}
+
+// GetRoleID gets the Discord id of a managed role
+func (database *Database) GetRoleID(key string) (string, bool) {
+	database.RLock()
+	roleID, ok := database.roles[key]
+	database.RUnlock()
+	return roleID, ok
+}
+
+// GetRoleIDs gets a copy of every managed role id, keyed by role key
+func (database *Database) GetRoleIDs() map[string]string {
+	database.RLock()
+	roles := maps.Clone(database.roles)
+	database.RUnlock()
+	return roles
+}
+
+// SetChannel records a spoiler channel, or forgets it once it has been reverted
+func (database *Database) SetChannel(channel EventChannel) error {
+	database.Lock()
+
+	if channel.Removed {
+		delete(database.channels, channel.ChannelID)
+	} else {
+		database.channels[channel.ChannelID] = channel
+	}
+
+	// Write the event to the database
+	err := database.writer.Encode(NewEventChannel(channel))
+
+	database.Unlock()
+	return err
+}
+
+// GetChannel gets a spoiler channel
+func (database *Database) GetChannel(channelID string) (EventChannel, bool) {
+	database.RLock()
+	channel, ok := database.channels[channelID]
+	database.RUnlock()
+	return channel, ok
+}
+
+// GetChannels gets every spoiler channel
+func (database *Database) GetChannels() []EventChannel {
+	database.RLock()
+	channels := slices.Collect(maps.Values(database.channels))
+	database.RUnlock()
+	return channels
+}
+
 // Snapshot takes a snapshot of the total scores
 func (database *Database) Snapshot(timestamp int64, scores map[string]int) error {
 	database.Lock()
 
 	// Take a snapshot of the total scores
-	database.scores = scores
+	database.snapshots = append(database.snapshots, EventSnapshot{Timestamp: timestamp, Scores: scores})
 
 	// Write the event to the database
 	err := database.writer.Encode(NewEventSnapshot(timestamp, scores))
@@ -201,28 +568,196 @@ func (database *Database) Snapshot(timestamp int64, scores map[string]int) error
 	return err
 }
 
+// LastSnapshot gets the most recent snapshot
+func (database *Database) LastSnapshot() (EventSnapshot, bool) {
+	database.RLock()
+	defer database.RUnlock()
+
+	if len(database.snapshots) == 0 {
+		return EventSnapshot{}, false
+	}
+
+	return database.snapshots[len(database.snapshots)-1], true
+}
+
+// SnapshotBefore gets the most recent snapshot taken at or before the given time
+//
+// If every snapshot is newer, the oldest snapshot is returned instead
+func (database *Database) SnapshotBefore(timestamp int64) (EventSnapshot, bool) {
+	database.RLock()
+	defer database.RUnlock()
+
+	if len(database.snapshots) == 0 {
+		return EventSnapshot{}, false
+	}
+
+	// Snapshots are appended in order, so search from the newest
+	for i := len(database.snapshots) - 1; i >= 0; i-- {
+		if database.snapshots[i].Timestamp <= timestamp {
+			return database.snapshots[i], true
+		}
+	}
+
+	return database.snapshots[0], true
+}
+
 // GetScores gets the change in total scores since the last snapshot
 func (database *Database) GetScores(currentScores map[string]int) map[string]int {
+	last, _ := database.LastSnapshot()
+	return ScoreDelta(last.Scores, currentScores)
+}
+
+// ScoreDelta gets the change in total scores between two snapshots
+//
+// Members missing from the earlier snapshot are treated as having had a score of 0
+func ScoreDelta(from, to map[string]int) map[string]int {
+	scores := make(map[string]int, len(to))
+	for id, score := range to {
+		scores[id] = score - from[id]
+	}
+	return scores
+}
+
+// StartSeason moves on to a new event year, archiving the previous year's snapshots
+func (database *Database) StartSeason(season EventSeason) error {
+	database.Lock()
+
+	database.seasons = append(database.seasons, season)
+	database.snapshots = nil
+
+	// Write the event to the database
+	err := database.writer.Encode(NewEventSeason(season))
+
+	database.Unlock()
+	return err
+}
+
+// GetSeasons gets every season that has started, oldest first
+func (database *Database) GetSeasons() []EventSeason {
 	database.RLock()
+	seasons := slices.Clone(database.seasons)
+	database.RUnlock()
+	return seasons
+}
+
+// SetVerification records a pending verification, or its outcome once it is no longer pending
+func (database *Database) SetVerification(verification EventVerification) error {
+	database.Lock()
 
-	// Get the change in total scores
-	scores := make(map[string]int)
-	for id, score := range currentScores {
-		scores[id] = score - database.scores[id]
+	if verification.Outcome != "" {
+		delete(database.verifications, verification.DiscordID)
+	} else {
+		database.verifications[verification.DiscordID] = verification
 	}
 
+	// Write the event to the database
+	err := database.writer.Encode(NewEventVerification(verification))
+
+	database.Unlock()
+	return err
+}
+
+// GetVerification gets a Discord user's pending verification
+func (database *Database) GetVerification(discordID string) (EventVerification, bool) {
+	database.RLock()
+	verification, ok := database.verifications[discordID]
 	database.RUnlock()
-	return scores
+	return verification, ok
 }
 
-// GoForEach iterates over each claim and calls a function
-func (database *Database) GoForEach(fn func(discord_id, advent_id string)) {
+// GetVerifications gets every pending verification
+func (database *Database) GetVerifications() []EventVerification {
 	database.RLock()
+	verifications := slices.Collect(maps.Values(database.verifications))
+	database.RUnlock()
+	return verifications
+}
 
-	// Iterate over each claim
-	for discordID, adventID := range database.mappings {
-		go fn(discordID, adventID)
+// RequestClaim records a claim that is waiting for approval, replacing the user's previous request
+//
+// Users who already claimed an account can't request another one, ErrHasClaim is returned instead
+func (database *Database) RequestClaim(discordID, adventID string, timestamp int64) error {
+	database.Lock()
+
+	if _, ok := database.mappings[discordID]; ok {
+		database.Unlock()
+		return ErrHasClaim
+	}
+
+	database.claimRequests[discordID] = EventClaimRequest{DiscordID: discordID, AdventID: adventID, Timestamp: timestamp}
+
+	// Write the event to the database
+	err := database.writer.Encode(NewEventClaimRequest(discordID, adventID, timestamp))
+
+	database.Unlock()
+	return err
+}
+
+// GetClaimRequest gets a Discord user's claim that is waiting for approval
+func (database *Database) GetClaimRequest(discordID string) (EventClaimRequest, bool) {
+	database.RLock()
+	request, ok := database.claimRequests[discordID]
+	database.RUnlock()
+	return request, ok
+}
+
+// DecideClaim records an admin's decision on a claim request, approved requests are claimed
+//
+// A request can't be approved once the account is claimed (ErrAlreadyClaimed) or the user claimed another account
+// (ErrHasClaim), those can only be rejected
+func (database *Database) DecideClaim(decision EventClaimDecision, approved bool) error {
+	database.Lock()
+
+	if approved {
+		if _, ok := database.mappings[decision.DiscordID]; ok {
+			database.Unlock()
+			return ErrHasClaim
+		}
+
+		for _, id := range database.mappings {
+			if id == decision.AdventID {
+				database.Unlock()
+				return ErrAlreadyClaimed
+			}
+		}
+	}
+
+	delete(database.claimRequests, decision.DiscordID)
+
+	// Write the event to the database
+	err := database.writer.Encode(NewEventClaimDecision(decision, approved))
+
+	if approved && err == nil {
+		database.mappings[decision.DiscordID] = decision.AdventID
+		err = database.writer.Encode(NewEventCreate(decision.DiscordID, decision.AdventID))
 	}
 
+	database.Unlock()
+	return err
+}
+
+// WithdrawClaim removes a claim request that never reached the admins, unless it was replaced by another one since
+func (database *Database) WithdrawClaim(discordID, adventID string) error {
+	database.Lock()
+
+	if request, ok := database.claimRequests[discordID]; !ok || request.AdventID != adventID {
+		database.Unlock()
+		return nil
+	}
+
+	delete(database.claimRequests, discordID)
+
+	// Write the event to the database
+	err := database.writer.Encode(NewEventClaimDecision(EventClaimDecision{DiscordID: discordID, AdventID: adventID}, false))
+
+	database.Unlock()
+	return err
+}
+
+// GetClaims gets a copy of every claim, keyed by Discord id
+func (database *Database) GetClaims() map[string]string {
+	database.RLock()
+	claims := maps.Clone(database.mappings)
 	database.RUnlock()
+	return claims
 }
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// replay opens a database from its log, anything written to it afterwards is discarded
+func replay(t *testing.T, log string) *Database {
+	t.Helper()
+
+	database, err := NewDatabase(strings.NewReader(log), io.Discard)
+	if err != nil {
+		t.Fatalf("NewDatabase() returned %v", err)
+	}
+	return database
+}
+
+// reopen opens a new database, lets write fill it in, and replays what was written
+func reopen(t *testing.T, write func(database *Database) error) (*Database, *Database) {
+	t.Helper()
+
+	var log bytes.Buffer
+	database, err := NewDatabase(strings.NewReader(""), &log)
+	if err != nil {
+		t.Fatalf("NewDatabase() returned %v", err)
+	}
+
+	err = write(database)
+	if err != nil {
+		t.Fatalf("writing the database returned %v", err)
+	}
+
+	return database, replay(t, log.String())
+}
+
+func TestNewDatabaseReplaySnapshots(t *testing.T) {
+	database := replay(t, `
+{"create":{"discord_id":"d1","aoc_id":"1"}}
+{"snapshot":{"timestamp":100,"scores":{"1":10}}}
+{"snapshot":{"timestamp":200,"scores":{"1":25,"2":5}}}
+`)
+
+	last, ok := database.LastSnapshot()
+	if !ok || last.Timestamp != 200 {
+		t.Fatalf("last snapshot = %v, %t, want the one from 200", last, ok)
+	}
+
+	want := map[string]int{"1": 5, "2": 0, "3": 7}
+	if scores := database.GetScores(map[string]int{"1": 30, "2": 5, "3": 7}); !maps.Equal(scores, want) {
+		t.Errorf("GetScores() = %v, want %v", scores, want)
+	}
+}
+
+func TestNewDatabaseRoundTripSnapshots(t *testing.T) {
+	_, replayed := reopen(t, func(database *Database) error {
+		return database.Snapshot(100, map[string]int{"1": 10})
+	})
+
+	last, ok := replayed.LastSnapshot()
+	if !ok || last.Timestamp != 100 || last.Scores["1"] != 10 {
+		t.Errorf("replayed snapshot = %v, %t, want the one from 100", last, ok)
+	}
+}
+
+func TestNewDatabaseReplaySeasons(t *testing.T) {
+	database := replay(t, `
+{"snapshot":{"timestamp":100,"scores":{"1":10}}}
+{"season":{"year":"2025","previous":"2024","timestamp":200,"roles":{"d1":["stars:50"]}}}
+{"snapshot":{"timestamp":300,"scores":{"1":20}}}
+`)
+
+	want := []EventSeason{{Year: "2025", Previous: "2024", Timestamp: 200, Roles: map[string][]string{"d1": {"stars:50"}}}}
+	if seasons := database.GetSeasons(); !reflect.DeepEqual(seasons, want) {
+		t.Errorf("seasons = %v, want %v", seasons, want)
+	}
+
+	// Snapshots of the previous season no longer count
+	if snapshot, _ := database.SnapshotBefore(0); snapshot.Timestamp != 300 {
+		t.Errorf("oldest snapshot is from %d, want 300", snapshot.Timestamp)
+	}
+}
+
+func TestNewDatabaseRoundTripSeasons(t *testing.T) {
+	season := EventSeason{Year: "2025", Previous: "2024", Timestamp: 200, Roles: map[string][]string{"d1": {"stars:50"}}}
+	database, replayed := reopen(t, func(database *Database) error {
+		return database.StartSeason(season)
+	})
+
+	if seasons, want := replayed.GetSeasons(), database.GetSeasons(); !reflect.DeepEqual(seasons, want) {
+		t.Errorf("replayed seasons = %v, want %v", seasons, want)
+	}
+}
+
+func TestNewDatabaseReplayVerifications(t *testing.T) {
+	database := replay(t, `
+{"verification":{"discord_id":"d1","aoc_id":"1","token":"AOC-ABCDEF","expires":1000}}
+{"verification":{"discord_id":"d2","aoc_id":"2","token":"AOC-GHIJKL","expires":1000}}
+{"verification":{"discord_id":"d2","aoc_id":"2","token":"AOC-GHIJKL","expires":1000,"outcome":"expired"}}
+`)
+
+	want := []EventVerification{{DiscordID: "d1", AdventID: "1", Token: "AOC-ABCDEF", Expires: 1000}}
+	if verifications := database.GetVerifications(); !reflect.DeepEqual(verifications, want) {
+		t.Errorf("pending verifications = %v, want %v", verifications, want)
+	}
+}
+
+func TestNewDatabaseReplayClaimRequests(t *testing.T) {
+	database := replay(t, `
+{"claim_request":{"discord_id":"d1","aoc_id":"1","timestamp":400}}
+{"claim_request":{"discord_id":"d2","aoc_id":"2","timestamp":400}}
+{"claim_approve":{"discord_id":"d2","aoc_id":"2","admin_id":"admin"}}
+{"create":{"discord_id":"d2","aoc_id":"2"}}
+{"claim_request":{"discord_id":"d3","aoc_id":"3","timestamp":400}}
+{"claim_reject":{"discord_id":"d3","aoc_id":"3","admin_id":"admin"}}
+`)
+
+	if request, ok := database.GetClaimRequest("d1"); !ok || request.AdventID != "1" || request.Timestamp != 400 {
+		t.Errorf("claim request of d1 = %v, %t, want the request of 1", request, ok)
+	}
+
+	for _, discordID := range []string{"d2", "d3"} {
+		if _, ok := database.GetClaimRequest(discordID); ok {
+			t.Errorf("claim request of %s is still pending after it was decided", discordID)
+		}
+	}
+
+	want := map[string]string{"d2": "2"}
+	if claims := database.GetClaims(); !maps.Equal(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+}
+
+func TestNewDatabaseRoundTripClaimRequests(t *testing.T) {
+	database, replayed := reopen(t, func(database *Database) error {
+		return errors.Join(
+			database.RequestClaim("d1", "1", 300),
+			database.RequestClaim("d2", "2", 300),
+			database.DecideClaim(EventClaimDecision{DiscordID: "d2", AdventID: "2", AdminID: "admin"}, true),
+			database.RequestClaim("d3", "3", 300),
+			database.WithdrawClaim("d3", "3"),
+		)
+	})
+
+	if claims, want := replayed.GetClaims(), database.GetClaims(); !maps.Equal(claims, want) {
+		t.Errorf("replayed claims = %v, want %v", claims, want)
+	}
+
+	for _, discordID := range []string{"d1", "d2", "d3"} {
+		request, ok := replayed.GetClaimRequest(discordID)
+		wantRequest, wantOK := database.GetClaimRequest(discordID)
+		if request != wantRequest || ok != wantOK {
+			t.Errorf("replayed claim request of %s = %v, %t, want %v, %t", discordID, request, ok, wantRequest, wantOK)
+		}
+	}
+}
+
+func TestDecideClaim(t *testing.T) {
+	database := replay(t, `
+{"create":{"discord_id":"d1","aoc_id":"1"}}
+{"claim_request":{"discord_id":"d1","aoc_id":"2","timestamp":100}}
+{"claim_request":{"discord_id":"d2","aoc_id":"1","timestamp":100}}
+{"claim_request":{"discord_id":"d3","aoc_id":"3","timestamp":100}}
+`)
+
+	err := database.DecideClaim(EventClaimDecision{DiscordID: "d1", AdventID: "2"}, true)
+	if !errors.Is(err, ErrHasClaim) {
+		t.Errorf("approving the request of a user with a claim returned %v, want ErrHasClaim", err)
+	}
+
+	err = database.DecideClaim(EventClaimDecision{DiscordID: "d2", AdventID: "1"}, true)
+	if !errors.Is(err, ErrAlreadyClaimed) {
+		t.Errorf("approving the request of a claimed account returned %v, want ErrAlreadyClaimed", err)
+	}
+
+	// Requests that can't be approved can still be rejected
+	err = database.DecideClaim(EventClaimDecision{DiscordID: "d2", AdventID: "1"}, false)
+	if err != nil {
+		t.Errorf("rejecting returned %v", err)
+	}
+
+	err = database.DecideClaim(EventClaimDecision{DiscordID: "d3", AdventID: "3"}, true)
+	if err != nil {
+		t.Errorf("approving returned %v", err)
+	}
+
+	want := map[string]string{"d1": "1", "d3": "3"}
+	if claims := database.GetClaims(); !maps.Equal(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+
+	if _, ok := database.GetClaimRequest("d1"); !ok {
+		t.Error("the request of d1 was dropped, even though it wasn't decided")
+	}
+}
+
+func TestRequestClaimWithClaim(t *testing.T) {
+	database := replay(t, `{"create":{"discord_id":"d1","aoc_id":"1"}}`)
+
+	if err := database.RequestClaim("d1", "2", 100); !errors.Is(err, ErrHasClaim) {
+		t.Errorf("RequestClaim() returned %v, want ErrHasClaim", err)
+	}
+}
+
+func TestWithdrawClaim(t *testing.T) {
+	database := replay(t, `{"claim_request":{"discord_id":"d1","aoc_id":"2","timestamp":100}}`)
+
+	// A request that was replaced since is left alone
+	if err := database.WithdrawClaim("d1", "1"); err != nil {
+		t.Fatalf("WithdrawClaim() returned %v", err)
+	}
+	if _, ok := database.GetClaimRequest("d1"); !ok {
+		t.Error("withdrawing another account dropped the request")
+	}
+
+	if err := database.WithdrawClaim("d1", "2"); err != nil {
+		t.Fatalf("WithdrawClaim() returned %v", err)
+	}
+	if _, ok := database.GetClaimRequest("d1"); ok {
+		t.Error("the request is still pending after it was withdrawn")
+	}
+}
+
+func TestNewDatabaseReplayLinks(t *testing.T) {
+	database := replay(t, `
+{"create":{"discord_id":"d1","aoc_id":"1"}}
+{"delete":{"discord_id":"d1"}}
+{"create":{"discord_id":"d2","aoc_id":"1","admin_id":"admin"}}
+`)
+
+	want := map[string]string{"d2": "1"}
+	if claims := database.GetClaims(); !maps.Equal(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+}
+
+func TestNewDatabaseRoundTripLinks(t *testing.T) {
+	var log bytes.Buffer
+	database, err := NewDatabase(strings.NewReader(`{"create":{"discord_id":"d1","aoc_id":"1"}}`), &log)
+	if err != nil {
+		t.Fatalf("NewDatabase() returned %v", err)
+	}
+
+	_, err = database.Link("d2", "1", "admin")
+	if err != nil {
+		t.Fatalf("Link() returned %v", err)
+	}
+
+	if !strings.Contains(log.String(), `"admin_id":"admin"`) {
+		t.Errorf("the link wasn't written with its admin:\n%s", log.String())
+	}
+
+	replayed := replay(t, `{"create":{"discord_id":"d1","aoc_id":"1"}}`+"\n"+log.String())
+	if claims, want := replayed.GetClaims(), database.GetClaims(); !maps.Equal(claims, want) {
+		t.Errorf("replayed claims = %v, want %v", claims, want)
+	}
+}
+
+func TestLink(t *testing.T) {
+	database := replay(t, `
+{"create":{"discord_id":"d1","aoc_id":"1"}}
+{"verification":{"discord_id":"d2","aoc_id":"3","token":"AOC-ABCDEF","expires":1000}}
+{"claim_request":{"discord_id":"d2","aoc_id":"4","timestamp":100}}
+`)
+
+	previous, err := database.Link("d2", "1", "admin")
+	if err != nil {
+		t.Fatalf("Link() returned %v", err)
+	}
+
+	if previous != "d1" {
+		t.Errorf("Link() took the account from %q, want d1", previous)
+	}
+
+	want := map[string]string{"d2": "1"}
+	if claims := database.GetClaims(); !maps.Equal(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+
+	if _, ok := database.GetVerification("d2"); ok {
+		t.Error("the verification of d2 is still pending after the link")
+	}
+
+	if _, ok := database.GetClaimRequest("d2"); ok {
+		t.Error("the claim request of d2 is still pending after the link")
+	}
+}
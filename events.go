@@ -0,0 +1,132 @@
+package main
+
+import "sort"
+
+// LeaderboardEvent is a single change between two fetches of a leaderboard
+//
+// It is one of StarEvent, JoinEvent, LeaveEvent or RenameEvent
+type LeaderboardEvent interface {
+	// MemberID is the Advent of Code id of the member the event is about
+	MemberID() string
+}
+
+// StarEvent is emitted when a member earns a new star
+type StarEvent struct {
+	ID        string
+	Name      string
+	Day       int
+	Part      int
+	Timestamp int64
+}
+
+// MemberID implements LeaderboardEvent
+func (event StarEvent) MemberID() string { return event.ID }
+
+// JoinEvent is emitted when a member joins the leaderboard
+type JoinEvent struct {
+	ID   string
+	Name string
+}
+
+// MemberID implements LeaderboardEvent
+func (event JoinEvent) MemberID() string { return event.ID }
+
+// LeaveEvent is emitted when a member leaves the leaderboard
+type LeaveEvent struct {
+	ID   string
+	Name string
+}
+
+// MemberID implements LeaderboardEvent
+func (event LeaveEvent) MemberID() string { return event.ID }
+
+// RenameEvent is emitted when a member changes their Advent of Code name
+type RenameEvent struct {
+	ID      string
+	OldName string
+	NewName string
+}
+
+// MemberID implements LeaderboardEvent
+func (event RenameEvent) MemberID() string { return event.ID }
+
+// DiffLeaderboards computes the events that turn the previous leaderboard into the next one
+//
+// When there is no previous leaderboard there is nothing to compare against, so no events are produced.
+// Events are ordered by member, with each member's stars in the order they were earned.
+func DiffLeaderboards(previous, next *Leaderboard) []LeaderboardEvent {
+	if previous == nil || next == nil {
+		return nil
+	}
+
+	var events []LeaderboardEvent
+
+	ids := make([]string, 0, len(next.Members))
+	for id := range next.Members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		member := next.Members[id]
+		old, ok := previous.Members[id]
+		if !ok {
+			events = append(events, JoinEvent{ID: id, Name: member.DisplayName()})
+			old = &Member{}
+		} else if old.Name != member.Name {
+			events = append(events, RenameEvent{ID: id, OldName: old.DisplayName(), NewName: member.DisplayName()})
+		}
+
+		events = append(events, diffStars(id, old, member)...)
+	}
+
+	var left []string
+	for id := range previous.Members {
+		if _, ok := next.Members[id]; !ok {
+			left = append(left, id)
+		}
+	}
+	sort.Strings(left)
+
+	for _, id := range left {
+		events = append(events, LeaveEvent{ID: id, Name: previous.Members[id].DisplayName()})
+	}
+
+	return events
+}
+
+// diffStars finds the stars a member has earned since the previous fetch
+func diffStars(id string, previous, next *Member) []LeaderboardEvent {
+	var stars []StarEvent
+	for day, parts := range next.CompletionDayLevel {
+		for part, level := range parts {
+			if _, ok := previous.CompletionDayLevel[day][part]; ok {
+				continue
+			}
+
+			stars = append(stars, StarEvent{
+				ID:        id,
+				Name:      next.DisplayName(),
+				Day:       day,
+				Part:      part,
+				Timestamp: int64(level.GetStarTS),
+			})
+		}
+	}
+
+	sort.Slice(stars, func(i, j int) bool {
+		if stars[i].Timestamp != stars[j].Timestamp {
+			return stars[i].Timestamp < stars[j].Timestamp
+		}
+		if stars[i].Day != stars[j].Day {
+			return stars[i].Day < stars[j].Day
+		}
+		return stars[i].Part < stars[j].Part
+	})
+
+	events := make([]LeaderboardEvent, len(stars))
+	for i, star := range stars {
+		events[i] = star
+	}
+	return events
+}
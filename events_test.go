@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLeaderboards(t *testing.T) {
+	previous := testLeaderboard("2024",
+		testMember(1, "alice", star{1, 1, 100}),
+		testMember(2, "bob"),
+		testMember(3, "carol"),
+	)
+	next := testLeaderboard("2024",
+		testMember(1, "alice", star{1, 1, 100}, star{2, 1, 300}, star{1, 2, 200}),
+		testMember(2, "robert"),
+		testMember(4, "dave", star{1, 1, 400}),
+	)
+
+	want := []LeaderboardEvent{
+		StarEvent{ID: "1", Name: "alice", Day: 1, Part: 2, Timestamp: 200},
+		StarEvent{ID: "1", Name: "alice", Day: 2, Part: 1, Timestamp: 300},
+		RenameEvent{ID: "2", OldName: "bob", NewName: "robert"},
+		JoinEvent{ID: "4", Name: "dave"},
+		StarEvent{ID: "4", Name: "dave", Day: 1, Part: 1, Timestamp: 400},
+		LeaveEvent{ID: "3", Name: "carol"},
+	}
+
+	got := DiffLeaderboards(previous, next)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffLeaderboards() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffLeaderboardsWithoutPrevious(t *testing.T) {
+	next := testLeaderboard("2024", testMember(1, "alice", star{1, 1, 100}))
+
+	if events := DiffLeaderboards(nil, next); len(events) != 0 {
+		t.Errorf("DiffLeaderboards(nil, next) = %v, want no events", events)
+	}
+
+	if events := DiffLeaderboards(next, nil); len(events) != 0 {
+		t.Errorf("DiffLeaderboards(next, nil) = %v, want no events", events)
+	}
+}
+
+func TestDiffLeaderboardsUnchanged(t *testing.T) {
+	leaderboard := testLeaderboard("2024", testMember(1, "alice", star{1, 1, 100}))
+
+	if events := DiffLeaderboards(leaderboard, leaderboard); len(events) != 0 {
+		t.Errorf("DiffLeaderboards() = %v, want no events", events)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeAdventOfCode serves scripted private leaderboards, so the bot can be rehearsed offline
+//
+// Point the bot at it with "aoc_url" in config.json. Any session cookie is accepted until the session is
+// expired, after which it serves HTML like the real site does.
+//
+// Besides the leaderboard API it has a few endpoints for driving the script:
+//
+//	POST /fake/star?member=ID[&day=D&part=P]  awards a star (the member's next one by default)
+//	POST /fake/join?name=NAME                  adds a member
+//	POST /fake/expire                          expires the session
+//	POST /fake/renew                           renews the session
+type FakeAdventOfCode struct {
+	sync.Mutex
+
+	year         string
+	leaderboards map[string]*Leaderboard
+	expired      bool
+}
+
+// RunFakeAdventOfCode parses the `fake-aoc` subcommand's flags and serves until it fails
+func RunFakeAdventOfCode(args []string) error {
+	flags := flag.NewFlagSet("fake-aoc", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	year := flags.String("year", strconv.Itoa(time.Now().Year()), "event year")
+	id := flags.String("id", "123456", "private leaderboard id")
+	script := flags.String("leaderboard", "", "leaderboard JSON to start from (optional)")
+	members := flags.Int("members", 5, "number of members to generate when no leaderboard is given")
+	starEvery := flags.Duration("star-every", 0, "award a random member their next star this often (0 disables)")
+	flags.Parse(args)
+
+	leaderboard := &Leaderboard{Event: *year, Members: make(map[string]*Member)}
+	if *script != "" {
+		file, err := os.Open(*script)
+		if err != nil {
+			return err
+		}
+
+		leaderboard, err = ParseLeaderboard(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	} else {
+		for i := 1; i <= *members; i++ {
+			leaderboard.Members[strconv.Itoa(i)] = &Member{ID: i, Name: fmt.Sprintf("member %d", i)}
+		}
+	}
+
+	ownerID, _ := strconv.Atoi(*id)
+	leaderboard.OwnerID = ownerID
+
+	fake := &FakeAdventOfCode{
+		year:         *year,
+		leaderboards: map[string]*Leaderboard{*id: leaderboard},
+	}
+	fake.recompute(leaderboard)
+
+	if *starEvery > 0 {
+		go func() {
+			for range time.Tick(*starEvery) {
+				fake.randomStar(*id)
+			}
+		}()
+	}
+
+	log.Printf("Fake Advent of Code serving leaderboard %s for %s on http://%s/\n", *id, *year, *addr)
+	return http.ListenAndServe(*addr, fake.Handler())
+}
+
+// Handler routes the fake's endpoints
+func (fake *FakeAdventOfCode) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{year}/leaderboard/private/view/{file}", fake.onLeaderboard)
+	mux.HandleFunc("POST /fake/star", fake.onStar)
+	mux.HandleFunc("POST /fake/join", fake.onJoin)
+	mux.HandleFunc("POST /fake/expire", func(w http.ResponseWriter, r *http.Request) { fake.setExpired(true) })
+	mux.HandleFunc("POST /fake/renew", func(w http.ResponseWriter, r *http.Request) { fake.setExpired(false) })
+	return mux
+}
+
+func (fake *FakeAdventOfCode) onLeaderboard(w http.ResponseWriter, r *http.Request) {
+	fake.Lock()
+	defer fake.Unlock()
+
+	// Without a valid session Advent of Code serves the login page
+	if cookie, err := r.Cookie("session"); fake.expired || err != nil || cookie.Value == "" {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>[Log In]</body></html>")
+		return
+	}
+
+	id, ok := strings.CutSuffix(r.PathValue("file"), ".json")
+	leaderboard, exists := fake.leaderboards[id]
+	if !ok || !exists || r.PathValue("year") != fake.year {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leaderboard)
+}
+
+func (fake *FakeAdventOfCode) onStar(w http.ResponseWriter, r *http.Request) {
+	fake.Lock()
+	defer fake.Unlock()
+
+	member, ok := fake.member(r.URL.Query().Get("member"))
+	if !ok {
+		http.Error(w, "no such member", http.StatusNotFound)
+		return
+	}
+
+	day, part := nextStar(member)
+	if r.URL.Query().Has("day") {
+		day, _ = strconv.Atoi(r.URL.Query().Get("day"))
+		part, _ = strconv.Atoi(r.URL.Query().Get("part"))
+	}
+
+	if day < 1 || day > EventDays(fake.year) || part < 1 || part > 2 {
+		http.Error(w, "no such star", http.StatusBadRequest)
+		return
+	}
+
+	fake.award(member, day, part)
+	log.Printf("Awarded %s day %d part %d\n", member.Name, day, part)
+}
+
+func (fake *FakeAdventOfCode) onJoin(w http.ResponseWriter, r *http.Request) {
+	fake.Lock()
+	defer fake.Unlock()
+
+	for _, leaderboard := range fake.leaderboards {
+		id := len(leaderboard.Members) + 1
+		for leaderboard.Members[strconv.Itoa(id)] != nil {
+			id++
+		}
+
+		leaderboard.Members[strconv.Itoa(id)] = &Member{ID: id, Name: r.URL.Query().Get("name")}
+		fake.recompute(leaderboard)
+		fmt.Fprintln(w, id)
+		log.Printf("Member %d joined\n", id)
+	}
+}
+
+func (fake *FakeAdventOfCode) setExpired(expired bool) {
+	fake.Lock()
+	fake.expired = expired
+	fake.Unlock()
+
+	log.Printf("Session expired: %t\n", expired)
+}
+
+// member finds a member on any leaderboard
+func (fake *FakeAdventOfCode) member(id string) (*Member, bool) {
+	for _, leaderboard := range fake.leaderboards {
+		if member, ok := leaderboard.Members[id]; ok {
+			return member, true
+		}
+	}
+	return nil, false
+}
+
+// randomStar awards a random member their next star
+func (fake *FakeAdventOfCode) randomStar(id string) {
+	fake.Lock()
+	defer fake.Unlock()
+
+	var candidates []*Member
+	for _, member := range fake.leaderboards[id].Members {
+		if day, _ := nextStar(member); day <= EventDays(fake.year) {
+			candidates = append(candidates, member)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return
+	}
+
+	member := candidates[rand.IntN(len(candidates))]
+	day, part := nextStar(member)
+	fake.award(member, day, part)
+	log.Printf("Awarded %s day %d part %d\n", member.Name, day, part)
+}
+
+// award gives a member a star now, and recomputes every score
+func (fake *FakeAdventOfCode) award(member *Member, day, part int) {
+	if member.CompletionDayLevel == nil {
+		member.CompletionDayLevel = make(map[int]map[int]*CompletionDayLevel)
+	}
+	if member.CompletionDayLevel[day] == nil {
+		member.CompletionDayLevel[day] = make(map[int]*CompletionDayLevel)
+	}
+	if _, ok := member.CompletionDayLevel[day][part]; ok {
+		return
+	}
+
+	member.CompletionDayLevel[day][part] = &CompletionDayLevel{GetStarTS: int(time.Now().Unix())}
+
+	for _, leaderboard := range fake.leaderboards {
+		fake.recompute(leaderboard)
+	}
+}
+
+// recompute updates stars, last star times and local scores from each member's completions
+func (fake *FakeAdventOfCode) recompute(leaderboard *Leaderboard) {
+	for _, member := range leaderboard.Members {
+		member.Stars = 0
+		member.LastStarTS = 0
+
+		for _, parts := range member.CompletionDayLevel {
+			for _, level := range parts {
+				member.Stars++
+				member.LastStarTS = max(member.LastStarTS, level.GetStarTS)
+			}
+		}
+	}
+
+	leaderboard.RecomputeLocalScores()
+}
+
+// nextStar is the first star a member hasn't earned yet
+func nextStar(member *Member) (int, int) {
+	for day := 1; ; day++ {
+		for part := 1; part <= 2; part++ {
+			if _, ok := member.CompletionDayLevel[day][part]; !ok {
+				return day, part
+			}
+		}
+	}
+}
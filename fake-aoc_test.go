@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// post calls one of the fake's script endpoints
+func post(t *testing.T, server *httptest.Server, path string) {
+	t.Helper()
+
+	response, err := http.Post(server.URL+path, "", nil)
+	if err != nil {
+		t.Fatalf("POST %s returned %v", path, err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s answered %s", path, response.Status)
+	}
+}
+
+func TestFakeAdventOfCode(t *testing.T) {
+	fake := &FakeAdventOfCode{
+		year:         "2024",
+		leaderboards: map[string]*Leaderboard{"123": testLeaderboard("2024", testMember(1, "alice"))},
+	}
+	server := httptest.NewServer(fake.Handler())
+	defer server.Close()
+
+	aoc := NewAdventOfCode("cookie", server.URL, t.TempDir())
+
+	post(t, server, "/fake/star?member=1")
+	leaderboard, _, err := aoc.fetch("123", "2024", "cookie")
+	if err != nil {
+		t.Fatalf("fetch() returned %v", err)
+	}
+	if alice := leaderboard.Members["1"]; alice.Stars != 1 || alice.CompletionDayLevel[1][1] == nil {
+		t.Errorf("after a star alice has %d stars, want day 1 part 1", alice.Stars)
+	}
+
+	post(t, server, "/fake/expire")
+	if _, _, err := aoc.fetch("123", "2024", "cookie"); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("fetch() of an expired session returned %v, want ErrInvalidSession", err)
+	}
+
+	post(t, server, "/fake/renew")
+	post(t, server, "/fake/star?member=1")
+	leaderboard, _, err = aoc.fetch("123", "2024", "cookie")
+	if err != nil {
+		t.Fatalf("fetch() of a renewed session returned %v", err)
+	}
+	if alice := leaderboard.Members["1"]; alice.Stars != 2 || alice.CompletionDayLevel[1][2] == nil {
+		t.Errorf("after another star alice has %d stars, want day 1 part 2 as well", alice.Stars)
+	}
+
+	// Like the real site, no cookie gets the login page
+	if _, _, err := aoc.fetch("123", "2024", ""); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("fetch() without a cookie returned %v, want ErrInvalidSession", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
+	"sort"
 
 	"github.com/hbollon/go-edlib"
 )
@@ -60,7 +62,13 @@ func ParseLeaderboard(r io.Reader) (*Leaderboard, error) {
 }
 
 // GetMemberByName gets a member by Name
+//
+// Like GetMemberByID it is safe to call on a nil leaderboard, which has no members
 func (leaderboard *Leaderboard) GetMemberByName(name string) (*Member, bool) {
+	if leaderboard == nil {
+		return nil, false
+	}
+
 	for _, member := range leaderboard.Members {
 		if member.Name == name {
 			return member, true
@@ -71,6 +79,10 @@ func (leaderboard *Leaderboard) GetMemberByName(name string) (*Member, bool) {
 
 // GetMemberByID gets a member by id
 func (leaderboard *Leaderboard) GetMemberByID(id string) (*Member, bool) {
+	if leaderboard == nil {
+		return nil, false
+	}
+
 	member, ok := leaderboard.Members[id]
 	return member, ok
 }
@@ -82,5 +94,126 @@ func (leaderboard *Leaderboard) CloseNames(name string) ([]string, error) {
 		names = append(names, member.Name)
 	}
 
-	return edlib.FuzzySearchSet(name, names, 3, edlib.Levenshtein)
+	return closestNames(name, names, 3)
+}
+
+// closestNames returns up to n names, ordered by how close they are to the given name
+func closestNames(name string, names []string, n int) ([]string, error) {
+	if n <= 0 || len(names) == 0 {
+		return nil, nil
+	}
+
+	closest, err := edlib.FuzzySearchSet(name, names, n, edlib.Levenshtein)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unfilled slots are left empty
+	return slices.DeleteFunc(closest, func(name string) bool { return name == "" }), nil
+}
+
+// Ranking is a member's position on the leaderboard under some scoring mode
+type Ranking struct {
+	Member *Member
+	Score  int
+}
+
+// Rank orders the members of the leaderboard by their score under the given scorer
+//
+// Ties are broken by stars, then by whoever earned their last star first, and finally by name
+func (leaderboard *Leaderboard) Rank(scorer Scorer) []Ranking {
+	scores := scorer.Scores(leaderboard)
+
+	rankings := make([]Ranking, 0, len(leaderboard.Members))
+	for id, member := range leaderboard.Members {
+		rankings = append(rankings, Ranking{Member: member, Score: scores[id]})
+	}
+
+	sort.Slice(rankings, func(i, j int) bool {
+		a, b := rankings[i], rankings[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Member.Stars != b.Member.Stars {
+			return a.Member.Stars > b.Member.Stars
+		}
+		if a.Member.LastStarTS != b.Member.LastStarTS {
+			return a.Member.LastStarTS < b.Member.LastStarTS
+		}
+		return a.Member.Name < b.Member.Name
+	})
+
+	return rankings
+}
+
+// DisplayName returns the member's name, falling back to the anonymous name Advent of Code uses
+func (member *Member) DisplayName() string {
+	if member.Name == "" {
+		return fmt.Sprintf("anonymous user #%d", member.ID)
+	}
+	return member.Name
+}
+
+// MergeLeaderboards merges several private leaderboards of the same event into one
+//
+// Members that appear on more than one leaderboard are only counted once. Local scores aren't comparable between
+// leaderboards, so they are recomputed over the merged set of members.
+func MergeLeaderboards(leaderboards ...*Leaderboard) *Leaderboard {
+	var merged *Leaderboard
+	for _, leaderboard := range leaderboards {
+		if leaderboard == nil {
+			continue
+		}
+
+		if merged == nil {
+			merged = &Leaderboard{
+				Event:   leaderboard.Event,
+				OwnerID: leaderboard.OwnerID,
+				Members: make(map[string]*Member),
+			}
+		}
+
+		for id, member := range leaderboard.Members {
+			// Boards are fetched at different times, so prefer whichever copy is more up to date
+			if existing, ok := merged.Members[id]; !ok || member.Stars > existing.Stars {
+				copied := *member
+				merged.Members[id] = &copied
+			}
+		}
+	}
+
+	if merged != nil {
+		merged.RecomputeLocalScores()
+	}
+
+	return merged
+}
+
+// RecomputeLocalScores recomputes the local score of every member the way Advent of Code does
+//
+// Each star is worth N points for the first member to earn it, N-1 for the second, and so on, where N is the
+// number of members on the leaderboard
+func (leaderboard *Leaderboard) RecomputeLocalScores() {
+	type star struct {
+		member *Member
+		ts     int
+	}
+
+	stars := make(map[[2]int][]star)
+	for _, member := range leaderboard.Members {
+		member.LocalScore = 0
+		for day, parts := range member.CompletionDayLevel {
+			for part, level := range parts {
+				stars[[2]int{day, part}] = append(stars[[2]int{day, part}], star{member, level.GetStarTS})
+			}
+		}
+	}
+
+	n := len(leaderboard.Members)
+	for _, earned := range stars {
+		sort.Slice(earned, func(i, j int) bool { return earned[i].ts < earned[j].ts })
+		for rank, s := range earned {
+			s.member.LocalScore += n - rank
+		}
+	}
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+// star is a star earned by a test member, see testMember
+type star struct {
+	day, part, ts int
+}
+
+// testMember creates a member that earned the given stars
+func testMember(id int, name string, stars ...star) *Member {
+	member := &Member{
+		ID:                 id,
+		Name:               name,
+		Stars:              len(stars),
+		CompletionDayLevel: make(map[int]map[int]*CompletionDayLevel),
+	}
+
+	for _, s := range stars {
+		if member.CompletionDayLevel[s.day] == nil {
+			member.CompletionDayLevel[s.day] = make(map[int]*CompletionDayLevel)
+		}
+		member.CompletionDayLevel[s.day][s.part] = &CompletionDayLevel{GetStarTS: s.ts}
+		member.LastStarTS = max(member.LastStarTS, s.ts)
+	}
+
+	return member
+}
+
+// testLeaderboard creates a leaderboard of the given members
+func testLeaderboard(event string, members ...*Member) *Leaderboard {
+	leaderboard := &Leaderboard{Event: event, Members: make(map[string]*Member)}
+	for _, member := range members {
+		leaderboard.Members[strconv.Itoa(member.ID)] = member
+	}
+	return leaderboard
+}
+
+func TestMergeLeaderboards(t *testing.T) {
+	a := testLeaderboard("2024",
+		testMember(1, "alice", star{1, 1, 100}, star{1, 2, 200}),
+		testMember(2, "bob", star{1, 1, 150}),
+	)
+	b := testLeaderboard("2024",
+		// Fetched earlier than a, so bob has fewer stars here
+		testMember(2, "bob"),
+		testMember(3, "carol", star{1, 1, 50}),
+	)
+
+	merged := MergeLeaderboards(a, nil, b)
+	if merged == nil {
+		t.Fatal("merged leaderboard is nil")
+	}
+
+	if merged.Event != "2024" {
+		t.Errorf("event = %q, want 2024", merged.Event)
+	}
+
+	if len(merged.Members) != 3 {
+		t.Fatalf("got %d members, want 3", len(merged.Members))
+	}
+
+	if stars := merged.Members["2"].Stars; stars != 1 {
+		t.Errorf("bob has %d stars, want the more up to date copy with 1", stars)
+	}
+
+	// Three members, so the first to earn a star gets 3 points, the second 2 and the third 1
+	want := map[string]int{
+		"1": 2 + 3,
+		"2": 1,
+		"3": 3,
+	}
+	for id, score := range want {
+		if got := merged.Members[id].LocalScore; got != score {
+			t.Errorf("member %s has local score %d, want %d", id, got, score)
+		}
+	}
+
+	// The leaderboards that were merged are left alone
+	if score := a.Members["1"].LocalScore; score != 0 {
+		t.Errorf("merging changed the local score of the source leaderboard to %d", score)
+	}
+}
+
+func TestMergeLeaderboardsNil(t *testing.T) {
+	if merged := MergeLeaderboards(nil, nil); merged != nil {
+		t.Errorf("merging only nil leaderboards = %v, want nil", merged)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Link links a Discord member to an Advent of Code account on behalf of an admin, and syncs everyone's roles
+//
+// Whoever claimed the account before loses it, along with their managed roles like after `/unclaim`. Roles are synced
+// on a best effort basis, the link itself is only undone by an error.
+func (bot *Bot) Link(guild *discordgo.Guild, guildMember *discordgo.Member, adventID string, adminID string) error {
+	guildState, ok := bot.states[guild.ID]
+	if !ok {
+		return ErrNotConfigured
+	}
+
+	previous, err := guildState.db.Link(guildMember.User.ID, adventID, adminID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Linked %s to %s on behalf of %s\n", guildMember.User.Username, adventID, adminID)
+
+	if previous != "" {
+		previousMember, err := bot.session.GuildMember(guild.ID, previous)
+		if err != nil {
+			log.Println("Error (Link) getting previous guild member: ", err)
+		} else if err = bot.RemoveAllRoles(guild, previousMember); err != nil {
+			log.Println("Error (Link) removing previous roles: ", err)
+		}
+	}
+
+	err = bot.SyncMemberRoles(guild, guildMember)
+	if err != nil {
+		log.Println("Error (Link) syncing roles: ", err)
+	}
+
+	return nil
+}
@@ -8,8 +8,20 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
+// Where the config is read from, and saved to when it is changed at runtime
+const configPath = "config.json"
+
 func main() {
-	file, err := os.Open("config.json")
+	// `aocbot fake-aoc` runs a local Advent of Code server instead of the bot
+	if len(os.Args) > 1 && os.Args[1] == "fake-aoc" {
+		err := RunFakeAdventOfCode(os.Args[2:])
+		if err != nil {
+			log.Fatalln("Error running fake Advent of Code: ", err)
+		}
+		return
+	}
+
+	file, err := os.Open(configPath)
 	if err != nil {
 		log.Fatalln("Error opening config file: ", err)
 	}
@@ -29,7 +41,7 @@ func main() {
 	}
 
 	// Create a new bot
-	bot := NewBot(session, config.SessionCookie)
+	bot := NewBot(session, config, configPath)
 
 	// Start the bot
 	err = bot.Start()
@@ -64,10 +76,14 @@ func main() {
 
 	log.Println("Press CTRL-C to exit.")
 
-	// Every 15 minutes, sync the bot with the Advent of Code API
-	bot.Sync()
+	// Snapshot scores each day at puzzle unlock
+	go bot.RunSnapshots()
+
+	// Sync everyone once, from then on only members whose progress changed are synced
+	bot.SyncAll()
 
-	ticker := time.NewTicker(15 * time.Minute)
+	// Sync with the Advent of Code API, ticking a little slower than the refresh throttle so that no tick is skipped
+	ticker := time.NewTicker(MinRefreshInterval + 30*time.Second)
 	for {
 		<-ticker.C
 		bot.Sync()
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnknownPeriod is returned when a movers period doesn't exist
+var ErrUnknownPeriod = errors.New("unknown movers period")
+
+// moverPeriods maps each movers period to how far back it looks, 0 looks back to the start of the event
+var moverPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+	"event":  0,
+}
+
+// The number of members listed in a movers report
+const moversShown = 10
+
+// Snapshots are taken a little after unlock, so a period is allowed to start this much late
+const snapshotGrace = 15 * time.Minute
+
+// Mover is a member whose score grew over a period
+type Mover struct {
+	Member *Member
+	Delta  int
+}
+
+// Movers ranks members by how much their score grew since the snapshot at the start of the period
+//
+// It also returns when the snapshot it compared against was taken, which is the zero time for "event"
+func (guildState *GuildState) Movers(period string, now time.Time) ([]Mover, time.Time, error) {
+	lookback, ok := moverPeriods[period]
+	if !ok {
+		return nil, time.Time{}, ErrUnknownPeriod
+	}
+
+	leaderboard := guildState.GetLeaderboard()
+	if leaderboard == nil {
+		return nil, time.Time{}, ErrDoesNotExist
+	}
+
+	var since time.Time
+	var baseline map[string]int
+	if lookback > 0 {
+		snapshot, ok := guildState.db.SnapshotBefore(now.Add(snapshotGrace - lookback).Unix())
+		if ok {
+			since = time.Unix(snapshot.Timestamp, 0)
+			baseline = snapshot.Scores
+		}
+	}
+
+	var movers []Mover
+	for id, delta := range ScoreDelta(baseline, guildState.scorer.Scores(leaderboard)) {
+		if delta > 0 {
+			movers = append(movers, Mover{Member: leaderboard.Members[id], Delta: delta})
+		}
+	}
+
+	sort.Slice(movers, func(i, j int) bool {
+		if movers[i].Delta != movers[j].Delta {
+			return movers[i].Delta > movers[j].Delta
+		}
+		return movers[i].Member.Name < movers[j].Member.Name
+	})
+
+	return movers, since, nil
+}
+
+// renderMovers renders the biggest movers over a period
+func (guildState *GuildState) renderMovers(period string, now time.Time) (string, error) {
+	movers, since, err := guildState.Movers(period, now)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if since.IsZero() {
+		sb.WriteString("**Biggest movers this event**\n")
+	} else {
+		fmt.Fprintf(&sb, "**Biggest movers since <t:%d:f>**\n", since.Unix())
+	}
+
+	if len(movers) == 0 {
+		sb.WriteString("Nobody has moved yet.")
+		return sb.String(), nil
+	}
+
+	discordIDs := guildState.db.GetDiscordIDs()
+	for rank, mover := range movers[:min(moversShown, len(movers))] {
+		fmt.Fprintf(&sb, "%d. %s: +%s\n", rank+1, mention(mover.Member, discordIDs), guildState.scorer.Format(mover.Delta))
+	}
+
+	return sb.String(), nil
+}
+
+// postMovers posts the scheduled movers report to the announcement channel, if one is due
+func (bot *Bot) postMovers(guildState *GuildState, now time.Time) {
+	if guildState.moversReport == "" || guildState.announceChannelID == "" {
+		return
+	}
+
+	// Weekly reports go out at the Monday unlock
+	if guildState.moversReport == "weekly" && now.In(est).Weekday() != time.Monday {
+		return
+	}
+
+	content, err := guildState.renderMovers(guildState.moversReport, now)
+	if err != nil {
+		return
+	}
+
+	bot.announce(guildState.announceChannelID, strings.Split(strings.TrimSpace(content), "\n"))
+}
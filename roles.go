@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// RolePurpose is why the bot manages a role
+type RolePurpose string
+
+// The purposes of managed roles
+const (
+	// RoleSpoiler gives access to every spoiler channel
+	RoleSpoiler RolePurpose = "spoiler"
+	// RoleConnected is given to everyone who has claimed an Advent of Code account
+	RoleConnected RolePurpose = "connected"
+	// RoleStars is given for reaching a tier, by the guild's scoring mode
+	RoleStars RolePurpose = "stars"
+	// RoleDay is given for completing a day, which unlocks its spoiler channel
+	RoleDay RolePurpose = "day"
+	// RoleTrophy is given for finishing a year's event, and is never taken away
+	RoleTrophy RolePurpose = "trophy"
+)
+
+// SpoilerRoleKey is the key of the spoiler role
+const SpoilerRoleKey = "spoiler"
+
+// ConnectedRoleKey is the key of the connected role
+const ConnectedRoleKey = "connected"
+
+// StarsRoleKey is the key of the role for reaching a tier, tiers are numbered by StarTiers whatever the scoring mode
+func StarsRoleKey(stars int) string {
+	return fmt.Sprintf("stars:%d", stars)
+}
+
+// DayRoleKey is the key of the role for completing a day
+func DayRoleKey(day int) string {
+	return fmt.Sprintf("day:%02d", day)
+}
+
+// TrophyRoleKey is the key of the role for finishing a year's event
+func TrophyRoleKey(year string) string {
+	return "trophy:" + year
+}
+
+// isTrophyRoleKey checks if a key is a trophy role's, including trophies for years the guild no longer follows
+func isTrophyRoleKey(key string) bool {
+	return strings.HasPrefix(key, "trophy:")
+}
+
+// ManagedRole is a role that the bot creates and hands out
+type ManagedRole struct {
+	// Key identifies the role, unlike the name it never changes
+	Key string
+	// Name is the name the role is created with, admins are free to rename it afterwards
+	Name        string
+	Color       int
+	Hoist       bool
+	Mentionable bool
+	Purpose     RolePurpose
+	// Stars is the tier of a RoleStars role, see StarTiers
+	Stars int
+}
+
+// The highest tier is gold, then each lower tier steps down the palette
+var starPalette = []int{
+	0xF1C40F, // Gold
+	0xE91E63, // Red/Pink
+	0x9B59B6, // Purple
+	0x3498DB, // Blue
+	0x1ABC9C, // Teal
+	0x2ECC71, // Green
+}
+
+// RoleRegistry is the single definition of every role the bot manages in a guild
+//
+// The Discord ids of the roles it creates are persisted in the database, so roles are found by id and
+// survive being renamed.
+type RoleRegistry struct {
+	roles []ManagedRole
+	db    *Database
+}
+
+// NewRoleRegistry defines the managed roles for an event of the given length
+//
+// Trophies maps each year the guild follows to the number of stars needed to finish it
+func NewRoleRegistry(db *Database, days int, dailyRoles bool, trophies map[string]int, scorer Scorer) *RoleRegistry {
+	roles := []ManagedRole{
+		{Key: SpoilerRoleKey, Name: "Spoiler", Mentionable: true, Purpose: RoleSpoiler},
+		{Key: ConnectedRoleKey, Name: "Connected", Color: 0x1ABC9C, Mentionable: true, Hoist: true, Purpose: RoleConnected},
+	}
+
+	tiers := StarTiers(days)
+	for i, stars := range tiers {
+		roles = append(roles, ManagedRole{
+			Key:         StarsRoleKey(stars),
+			Name:        tierRoleName(scorer, i, stars),
+			Color:       starPalette[min(len(tiers)-1-i, len(starPalette)-1)],
+			Mentionable: true,
+			Hoist:       true,
+			Purpose:     RoleStars,
+			Stars:       stars,
+		})
+	}
+
+	years := slices.Sorted(maps.Keys(trophies))
+	slices.Reverse(years)
+	for _, year := range years {
+		roles = append(roles, ManagedRole{
+			Key:     TrophyRoleKey(year),
+			Name:    fmt.Sprintf("AoC %s ⭐%d", year, trophies[year]),
+			Color:   0xE67E22,
+			Purpose: RoleTrophy,
+		})
+	}
+
+	if dailyRoles {
+		for day := days; day > 0; day-- {
+			roles = append(roles, ManagedRole{
+				Key:     DayRoleKey(day),
+				Name:    fmt.Sprintf("Day %02d", day),
+				Purpose: RoleDay,
+			})
+		}
+	}
+
+	return &RoleRegistry{roles: roles, db: db}
+}
+
+// tierRoleName names the role of a tier, only roles earned by stars are named after them
+func tierRoleName(scorer Scorer, tier, stars int) string {
+	if _, ok := scorer.(StarScorer); ok {
+		return fmt.Sprintf("%d Stars", stars)
+	}
+	return fmt.Sprintf("Tier %d", tier+1)
+}
+
+// Roles gets every managed role, in the order they are created
+func (registry *RoleRegistry) Roles() []ManagedRole {
+	return registry.roles
+}
+
+// Get gets a managed role by key
+func (registry *RoleRegistry) Get(key string) (ManagedRole, bool) {
+	for _, role := range registry.roles {
+		if role.Key == key {
+			return role, true
+		}
+	}
+	return ManagedRole{}, false
+}
+
+// ID gets the Discord id of a managed role
+//
+// Roles created before their ids were stored are adopted by name the first time they are looked up.
+func (registry *RoleRegistry) ID(guild *discordgo.Guild, key string) (string, bool) {
+	if roleID, ok := registry.db.GetRoleID(key); ok && findRole(guild, roleID) != nil {
+		return roleID, true
+	}
+
+	role, ok := registry.Get(key)
+	if !ok {
+		return "", false
+	}
+
+	for _, guildRole := range guild.Roles {
+		if guildRole.Name == role.Name {
+			err := registry.db.SetRoleID(key, guildRole.ID)
+			if err != nil {
+				log.Println("Error (RoleRegistry.ID) saving role id: ", err)
+			}
+			return guildRole.ID, true
+		}
+	}
+
+	return "", false
+}
+
+// Key gets the key of a Discord role, if it is one the bot created
+//
+// This includes roles that are no longer defined, e.g. day roles after daily roles were turned off
+func (registry *RoleRegistry) Key(roleID string) (string, bool) {
+	for key, id := range registry.db.GetRoleIDs() {
+		if id == roleID {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Ensure creates every managed role that is missing from the guild
+func (registry *RoleRegistry) Ensure(session *discordgo.Session, guild *discordgo.Guild) error {
+	for _, role := range registry.roles {
+		if _, ok := registry.ID(guild, role.Key); ok {
+			continue
+		}
+
+		params := &discordgo.RoleParams{
+			Name:        role.Name,
+			Hoist:       &role.Hoist,
+			Mentionable: &role.Mentionable,
+		}
+		if role.Color != 0 {
+			params.Color = &role.Color
+		}
+
+		created, err := session.GuildRoleCreate(guild.ID, params)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Created role %s in %s\n", role.Name, guild.Name)
+		err = registry.db.SetRoleID(role.Key, created.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findRole finds a role in a guild by id
+func findRole(guild *discordgo.Guild, roleID string) *discordgo.Role {
+	for _, role := range guild.Roles {
+		if role.ID == roleID {
+			return role
+		}
+	}
+	return nil
+}
+
+// DesiredRoles computes the set of managed roles a member should have given their progress, by key
+//
+// Tier roles are earned by the member's score under the guild's scoring mode, scores holds everyone's since some modes
+// score members against each other. The spoiler role is never included, members choose that one themselves. Trophies
+// are included for every year the guild follows that the member finished.
+func (guildState *GuildState) DesiredRoles(state *yearState, member *Member, scores map[string]int) map[string]bool {
+	desired := map[string]bool{
+		ConnectedRoleKey: true,
+	}
+
+	score := scores[fmt.Sprint(member.ID)]
+	thresholds := guildState.scorer.Tiers(state.days, len(scores))
+	for i, stars := range StarTiers(state.days) {
+		if score >= thresholds[i] {
+			desired[StarsRoleKey(stars)] = true
+		}
+	}
+
+	if guildState.daily_roles {
+		for day := 1; day <= state.days; day++ {
+			if len(member.CompletionDayLevel[day]) > 0 {
+				desired[DayRoleKey(day)] = true
+			}
+		}
+	}
+
+	for _, progress := range guildState.Progress(state, fmt.Sprint(member.ID), false) {
+		if progress.Finished() {
+			desired[TrophyRoleKey(progress.Year)] = true
+		}
+	}
+
+	return desired
+}
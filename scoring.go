@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrUnknownMode is returned when a guild is configured with a scoring mode that doesn't exist
+var ErrUnknownMode = errors.New("unknown scoring mode")
+
+// Scorer is a strategy for scoring the members of a leaderboard
+//
+// Higher scores always rank first. Scorers decide rankings (`/leaderboard`, movers and snapshots) as well as who earns
+// each tier role, see DesiredRoles.
+type Scorer interface {
+	// Scores computes the score of every member of the leaderboard, keyed by Advent of Code id
+	Scores(leaderboard *Leaderboard) map[string]int
+	// Format renders a score for display
+	Format(score int) string
+	// Tiers gets the score that earns each tier role on a leaderboard with the given number of members, one for each
+	// of StarTiers(days)
+	Tiers(days, members int) []int
+}
+
+// scorers maps each mode name to its scorer
+var scorers = map[string]Scorer{
+	"local":         LocalScorer{},
+	"stars":         StarScorer{},
+	"time-to-solve": TimeToSolveScorer{},
+	"part2-delta":   Part2DeltaScorer{},
+}
+
+// NewScorer gets the scorer for a mode, an empty mode uses Advent of Code's local score
+func NewScorer(mode string) (Scorer, error) {
+	if mode == "" {
+		mode = "local"
+	}
+
+	scorer, ok := scorers[mode]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+
+	return scorer, nil
+}
+
+// scaleTiers gives each of StarTiers(days) the score of earning that many stars at numerator/denominator points a star
+func scaleTiers(days, numerator, denominator int) []int {
+	tiers := StarTiers(days)
+	for i, stars := range tiers {
+		tiers[i] = max(1, stars*numerator/denominator)
+	}
+	return tiers
+}
+
+// LocalScorer uses the local score computed by Advent of Code
+type LocalScorer struct{}
+
+// Scores implements Scorer
+func (LocalScorer) Scores(leaderboard *Leaderboard) map[string]int {
+	scores := make(map[string]int, len(leaderboard.Members))
+	for id, member := range leaderboard.Members {
+		scores[id] = member.LocalScore
+	}
+	return scores
+}
+
+// Format implements Scorer
+func (LocalScorer) Format(score int) string {
+	return fmt.Sprintf("%d points", score)
+}
+
+// Tiers implements Scorer, a tier is worth as many points as its stars earned halfway down the leaderboard
+func (LocalScorer) Tiers(days, members int) []int {
+	return scaleTiers(days, members+1, 2)
+}
+
+// StarScorer scores members by the number of stars they have collected
+type StarScorer struct{}
+
+// Scores implements Scorer
+func (StarScorer) Scores(leaderboard *Leaderboard) map[string]int {
+	scores := make(map[string]int, len(leaderboard.Members))
+	for id, member := range leaderboard.Members {
+		scores[id] = member.Stars
+	}
+	return scores
+}
+
+// Format implements Scorer
+func (StarScorer) Format(score int) string {
+	return fmt.Sprintf("%d stars", score)
+}
+
+// Tiers implements Scorer, the tiers are the star counts themselves
+func (StarScorer) Tiers(days, members int) []int {
+	return StarTiers(days)
+}
+
+// TimeToSolveScorer scores each star by how soon after the puzzle unlocked it was earned
+//
+// A star earned within the first hour is worth 24 points, and every hour after that costs a point, down to 1.
+// Unlike the local score this doesn't depend on how many people are on the leaderboard.
+type TimeToSolveScorer struct{}
+
+// Scores implements Scorer
+func (TimeToSolveScorer) Scores(leaderboard *Leaderboard) map[string]int {
+	scores := make(map[string]int, len(leaderboard.Members))
+	for id, member := range leaderboard.Members {
+		score := 0
+		for day, parts := range member.CompletionDayLevel {
+			unlock := UnlockTime(leaderboard.Event, day)
+			for _, part := range parts {
+				hours := int(time.Unix(int64(part.GetStarTS), 0).Sub(unlock).Hours())
+				score += max(1, 24-hours)
+			}
+		}
+		scores[id] = score
+	}
+	return scores
+}
+
+// Format implements Scorer
+func (TimeToSolveScorer) Format(score int) string {
+	return fmt.Sprintf("%d points", score)
+}
+
+// Tiers implements Scorer, a tier is worth as many points as its stars earned half a day after they unlocked
+func (TimeToSolveScorer) Tiers(days, members int) []int {
+	return scaleTiers(days, 12, 1)
+}
+
+// Part2DeltaScorer scores members by how long they took to get from part 1 to part 2
+//
+// For each day, members who finished both parts are ranked by that delta, the fastest receiving N points
+// (where N is the number of members on the leaderboard), the next N-1, and so on.
+// This rewards solving skill without penalizing anyone for when the puzzle unlocks in their timezone.
+type Part2DeltaScorer struct{}
+
+// Scores implements Scorer
+func (Part2DeltaScorer) Scores(leaderboard *Leaderboard) map[string]int {
+	type delta struct {
+		id    string
+		delta int
+	}
+
+	// Group the deltas by day
+	days := make(map[int][]delta)
+	for id, member := range leaderboard.Members {
+		for day, parts := range member.CompletionDayLevel {
+			part1, ok1 := parts[1]
+			part2, ok2 := parts[2]
+			if ok1 && ok2 {
+				days[day] = append(days[day], delta{id, part2.GetStarTS - part1.GetStarTS})
+			}
+		}
+	}
+
+	scores := make(map[string]int, len(leaderboard.Members))
+	for id := range leaderboard.Members {
+		scores[id] = 0
+	}
+
+	n := len(leaderboard.Members)
+	for _, deltas := range days {
+		sort.Slice(deltas, func(i, j int) bool {
+			if deltas[i].delta != deltas[j].delta {
+				return deltas[i].delta < deltas[j].delta
+			}
+			return deltas[i].id < deltas[j].id
+		})
+
+		for rank, d := range deltas {
+			scores[d.id] += n - rank
+		}
+	}
+
+	return scores
+}
+
+// Format implements Scorer
+func (Part2DeltaScorer) Format(score int) string {
+	return fmt.Sprintf("%d points", score)
+}
+
+// Tiers implements Scorer, a tier is worth as many points as the days of its stars finished halfway down the leaderboard
+func (Part2DeltaScorer) Tiers(days, members int) []int {
+	return scaleTiers(days, members+1, 4)
+}
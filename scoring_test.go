@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewScorer(t *testing.T) {
+	scorer, err := NewScorer("")
+	if err != nil {
+		t.Fatalf("NewScorer(\"\") returned %v", err)
+	}
+	if _, ok := scorer.(LocalScorer); !ok {
+		t.Errorf("NewScorer(\"\") = %T, want LocalScorer", scorer)
+	}
+
+	for mode, want := range scorers {
+		scorer, err := NewScorer(mode)
+		if err != nil || scorer != want {
+			t.Errorf("NewScorer(%q) = %T, %v, want %T", mode, scorer, err, want)
+		}
+	}
+
+	_, err = NewScorer("fastest")
+	if !errors.Is(err, ErrUnknownMode) {
+		t.Errorf("NewScorer(\"fastest\") returned %v, want ErrUnknownMode", err)
+	}
+}
+
+func TestLocalScorer(t *testing.T) {
+	alice := testMember(1, "alice")
+	alice.LocalScore = 42
+	leaderboard := testLeaderboard("2024", alice, testMember(2, "bob"))
+
+	want := map[string]int{"1": 42, "2": 0}
+	if got := (LocalScorer{}).Scores(leaderboard); !maps.Equal(got, want) {
+		t.Errorf("Scores() = %v, want %v", got, want)
+	}
+}
+
+func TestStarScorer(t *testing.T) {
+	leaderboard := testLeaderboard("2024",
+		testMember(1, "alice", star{1, 1, 100}, star{1, 2, 200}),
+		testMember(2, "bob"),
+	)
+
+	want := map[string]int{"1": 2, "2": 0}
+	if got := (StarScorer{}).Scores(leaderboard); !maps.Equal(got, want) {
+		t.Errorf("Scores() = %v, want %v", got, want)
+	}
+}
+
+func TestTimeToSolveScorer(t *testing.T) {
+	after := func(day int, d time.Duration) int {
+		return int(UnlockTime("2024", day).Add(d).Unix())
+	}
+
+	leaderboard := testLeaderboard("2024",
+		testMember(1, "alice", star{1, 1, after(1, 30*time.Minute)}, star{1, 2, after(1, 5*time.Hour+30*time.Minute)}),
+		testMember(2, "bob", star{2, 1, after(2, 30*time.Hour)}),
+		testMember(3, "carol"),
+	)
+
+	// 24 points within the first hour, a point less every hour after that, and at least 1
+	want := map[string]int{"1": 24 + 19, "2": 1, "3": 0}
+	if got := (TimeToSolveScorer{}).Scores(leaderboard); !maps.Equal(got, want) {
+		t.Errorf("Scores() = %v, want %v", got, want)
+	}
+}
+
+func TestPart2DeltaScorer(t *testing.T) {
+	leaderboard := testLeaderboard("2024",
+		testMember(1, "alice", star{1, 1, 100}, star{1, 2, 200}, star{2, 1, 1000}, star{2, 2, 1010}),
+		testMember(2, "bob", star{1, 1, 500}, star{1, 2, 550}),
+		testMember(3, "carol", star{1, 1, 50}),
+	)
+
+	// On day 1 bob was faster than alice and carol didn't finish, on day 2 only alice finished
+	want := map[string]int{"1": 2 + 3, "2": 3, "3": 0}
+	if got := (Part2DeltaScorer{}).Scores(leaderboard); !maps.Equal(got, want) {
+		t.Errorf("Scores() = %v, want %v", got, want)
+	}
+}
+
+func TestScorerTiers(t *testing.T) {
+	if got, want := (StarScorer{}).Tiers(12, 3), StarTiers(12); !slices.Equal(got, want) {
+		t.Errorf("StarScorer.Tiers() = %v, want the star tiers %v", got, want)
+	}
+
+	// Every scorer has a rising threshold for each star tier, even on a leaderboard of its own
+	for mode, scorer := range scorers {
+		for _, members := range []int{0, 1, 200} {
+			tiers := scorer.Tiers(25, members)
+			if len(tiers) != len(StarTiers(25)) || !slices.IsSorted(tiers) || tiers[0] < 1 {
+				t.Errorf("%s.Tiers(25, %d) = %v, want one rising threshold above 0 for each star tier", mode, members, tiers)
+			}
+		}
+	}
+
+	want := []int{8, 16, 24, 32, 40, 48}
+	if got := (LocalScorer{}).Tiers(12, 3); !slices.Equal(got, want) {
+		t.Errorf("LocalScorer.Tiers(12, 3) = %v, want %v", got, want)
+	}
+}
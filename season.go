@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	// Embedded so the calendar works on hosts without a timezone database
+	_ "time/tzdata"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// AutoYear is the GuildConfig.Year of guilds that follow the calendar
+const AutoYear = "auto"
+
+// newYork is the timezone Advent of Code's calendar follows
+var newYork = mustLoadLocation("America/New_York")
+
+// mustLoadLocation loads a timezone, which is embedded so it can't be missing
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// ActiveYear returns the event year that is running at the given time
+//
+// Each event starts on the 1st of December, until then the previous year's event is the active one
+func ActiveYear(t time.Time) string {
+	t = t.In(newYork)
+
+	year := t.Year()
+	if t.Month() < time.December {
+		year--
+	}
+
+	return strconv.Itoa(year)
+}
+
+// rollover moves a guild that follows the calendar on to the new event, once Advent of Code confirms it has started
+//
+// The roles each claimed member held are archived and the previous year's snapshots no longer count. The new year's
+// roles are created, everyone's roles are synced to the new year, and the new season is announced.
+func (bot *Bot) rollover(guild *discordgo.Guild, guildState *GuildState, now time.Time) error {
+	previous := guildState.current.Load()
+	year := ActiveYear(now)
+	if !guildState.followCalendar || year <= previous.year {
+		return nil
+	}
+
+	// Private leaderboards of an event that hasn't started can't be fetched
+	leaderboard := guildState.GetYearLeaderboard(year)
+	if leaderboard == nil || leaderboard.Event != year {
+		log.Printf("Advent of Code %s hasn't started yet for %s\n", year, guild.Name)
+		return nil
+	}
+
+	log.Printf("Rolling %s over from %s to %s\n", guild.Name, previous.year, year)
+
+	season := EventSeason{
+		Year:      year,
+		Previous:  previous.year,
+		Timestamp: now.Unix(),
+		Roles:     make(map[string][]string),
+	}
+
+	for discordID := range guildState.db.GetClaims() {
+		guildMember, err := bot.session.GuildMember(guild.ID, discordID)
+		if err != nil {
+			log.Printf("Error (rollover) getting guild member %s: %s\n", discordID, err)
+			continue
+		}
+
+		for _, roleID := range guildMember.Roles {
+			if key, managed := previous.roles.Key(roleID); managed {
+				season.Roles[discordID] = append(season.Roles[discordID], key)
+			}
+		}
+	}
+
+	err := guildState.StartSeason(season)
+	if err != nil {
+		return err
+	}
+
+	// Subscribe to the new year before anyone's progress in it can change
+	bot.subscribe(guild.ID, guildState, year)
+
+	err = bot.CreateRoles(guild)
+	if err != nil {
+		return err
+	}
+
+	if guildState.announceChannelID != "" {
+		msg := fmt.Sprintf(":christmas_tree: **Advent of Code %s** has begun! There are %d days of puzzles this year. Everyone's tier roles start over, and `/leaderboard year:%s` still shows last year.", year, guildState.current.Load().days, season.Previous)
+		bot.announce(guildState.announceChannelID, []string{msg})
+	}
+
+	return bot.SyncAllRoles(guild)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// refreshGuild refreshes a guild's leaderboard and tracks whether Advent of Code accepts its session cookie
+//
+// Admins are notified once when the session expires and once when it works again, not on every refresh
+func (bot *Bot) refreshGuild(guildID string, guildState *GuildState) error {
+	err := guildState.Refresh()
+
+	switch {
+	case errors.Is(err, ErrInvalidSession):
+		if guildState.degraded.CompareAndSwap(false, true) {
+			log.Printf("Guild %s is degraded: %s\n", guildID, err)
+			bot.notifyAdmins(guildState, "The Advent of Code session cookie has expired, so I can't see the leaderboard. "+
+				"Roles and announcements are paused until an admin provides a new one with `/session`.")
+		}
+	case err == nil:
+		if guildState.degraded.CompareAndSwap(true, false) {
+			log.Printf("Guild %s has recovered\n", guildID)
+			bot.notifyAdmins(guildState, "The Advent of Code session cookie works again :tada:")
+		}
+	}
+
+	return err
+}
+
+// notifyAdmins posts a message in the guild's admin channel, or sends it to the bot's owners if there isn't one
+func (bot *Bot) notifyAdmins(guildState *GuildState, msg string) {
+	if guildState.adminChannelID != "" {
+		_, err := bot.session.ChannelMessageSend(guildState.adminChannelID, msg)
+		if err != nil {
+			log.Println("Error (notifyAdmins) sending message: ", err)
+		}
+		return
+	}
+
+	for _, owner := range bot.config.Owners {
+		err := bot.directMessage(owner, msg)
+		if err != nil {
+			log.Println("Error (notifyAdmins) sending DM: ", err)
+		}
+	}
+}
+
+// onSession opens a modal asking an admin for a new session cookie, it is never echoed back into a channel
+func (bot *Bot) onSession(interaction *discordgo.Interaction) {
+	log.Printf("Session update requested by @%s", interaction.Member.User.Username)
+
+	if !bot.IsAdmin(interaction.Member) {
+		bot.respondToInteraction(interaction, "Error 29: You must be an admin to update the session cookie.", true)
+		return
+	}
+
+	err := bot.session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "session",
+			Title:    "Advent of Code session cookie",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "cookie",
+							Label:       "Session cookie",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "The value of the 'session' cookie on adventofcode.com",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		log.Println("onSession failed while responding to interaction: ", err)
+	}
+}
+
+// onSessionSubmit validates and saves the session cookie submitted through the `/session` modal
+//
+// Every guild without a cookie of its own shares the global session, so only the bot's owners may replace it. An admin
+// of such a guild gets a session of its own instead.
+func (bot *Bot) onSessionSubmit(interaction *discordgo.Interaction) {
+	deferred := bot.deferInteraction(interaction, true)
+
+	if !bot.IsAdmin(interaction.Member) {
+		deferred.finalize("Error 29: You must be an admin to update the session cookie.")
+		return
+	}
+
+	guildState, ok := bot.states[interaction.GuildID]
+	if !ok {
+		deferred.finalize("Error 30: This guild is not configured, yet.")
+		return
+	}
+
+	cookie := modalValue(interaction.ModalSubmitData(), "cookie")
+
+	// A guild's own session is named after it, see NewGuildState
+	previous := guildState.Session()
+	session := previous
+	if session == "" && !bot.IsOwner(interaction.Member.User.ID) {
+		session = interaction.GuildID
+	}
+
+	err := bot.adventOfCode.UpdateSession(session, cookie, guildState.leaderboardIDs[0], guildState.current.Load().year)
+	if errors.Is(err, ErrInvalidSession) {
+		deferred.finalize("Error 31: Advent of Code rejected that session cookie.")
+		return
+	} else if err != nil {
+		log.Println("Error (onSessionSubmit) validating session: ", err)
+		deferred.finalize("Error 32: I couldn't reach Advent of Code to check that session cookie, please try again later.")
+		return
+	}
+
+	log.Printf("Session cookie updated by @%s", interaction.Member.User.Username)
+
+	if session != previous {
+		log.Printf("Guild %s moved to its own session\n", interaction.GuildID)
+		guildState.session.Store(&session)
+		bot.subscribe(interaction.GuildID, guildState, guildState.current.Load().year)
+	}
+
+	err = bot.saveSession(interaction.GuildID, session, cookie)
+	if err != nil {
+		log.Println("Error (onSessionSubmit) saving config: ", err)
+		deferred.finalize("Error 33: The session cookie works, but I couldn't save it. It will be lost when I restart.")
+	} else if session != previous {
+		deferred.finalize("Success: This server now uses its own session cookie, the one shared with other servers is left alone.")
+	} else {
+		deferred.finalize("Success: The session cookie has been updated!")
+	}
+
+	// Every degraded guild sharing this session may have recovered, a guild that just moved to its own session may
+	// have missed changes while it did
+	for guildID, other := range bot.states {
+		if other.Session() == session && other.degraded.Load() {
+			_ = bot.refreshGuild(guildID, other)
+		}
+	}
+
+	if session != previous {
+		if events := guildState.Changes(); len(events) > 0 {
+			bot.onLeaderboardEvents(interaction.GuildID, events)
+		}
+	}
+}
+
+// saveSession records the cookie of a session in the config and saves it, the global session is ""
+func (bot *Bot) saveSession(guildID, session, cookie string) error {
+	bot.configLock.Lock()
+	defer bot.configLock.Unlock()
+
+	if session != "" {
+		guildConfig := bot.config.Guilds[guildID]
+		guildConfig.SessionCookie = cookie
+		bot.config.Guilds[guildID] = guildConfig
+	} else {
+		bot.config.SessionCookie = cookie
+	}
+
+	return bot.saveConfig()
+}
+
+// saveConfig writes the config back to where it was read from, the caller holds configLock
+func (bot *Bot) saveConfig() error {
+	var buffer bytes.Buffer
+	err := bot.config.Save(&buffer)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename, so a crash never leaves a partial config behind. It holds the Discord token, so only we can read it.
+	err = os.WriteFile(bot.configPath+".tmp", buffer.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(bot.configPath+".tmp", bot.configPath)
+}
+
+// modalValue gets the value of a text input in a submitted modal
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, component := range data.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+
+		for _, component := range row.Components {
+			if input, ok := component.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+	"maps"
+	"time"
+)
+
+// previousUnlock returns the most recent midnight EST, when the day's puzzle unlocked
+func previousUnlock(t time.Time) time.Time {
+	t = t.In(est)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, est)
+}
+
+// RunSnapshots takes a snapshot of every guild's scores each day when the puzzle unlocks
+//
+// Snapshots missed while the bot was offline are taken as soon as it starts. This never returns.
+//
+// Scheduled movers reports are posted right after each unlock's snapshot.
+func (bot *Bot) RunSnapshots() {
+	for first := true; ; first = false {
+		unlock := previousUnlock(time.Now())
+
+		for guildID, guildState := range bot.states {
+			// Reports are only due at unlock, not when catching up after a restart
+			if !first {
+				bot.postMovers(guildState, time.Now())
+			}
+
+			last, ok := guildState.db.LastSnapshot()
+			if ok && last.Timestamp >= unlock.Unix() {
+				continue
+			}
+
+			err := guildState.Snapshot(time.Now())
+			if err != nil {
+				log.Printf("Error (RunSnapshots) taking snapshot for %s: %s\n", guildID, err)
+			}
+		}
+
+		time.Sleep(time.Until(unlock.AddDate(0, 0, 1)))
+	}
+}
+
+// Snapshot records every member's current score, unless nothing changed since the last snapshot
+func (guildState *GuildState) Snapshot(timestamp time.Time) error {
+	leaderboard := guildState.GetLeaderboard()
+	if leaderboard == nil {
+		return ErrDoesNotExist
+	}
+
+	scores := guildState.scorer.Scores(leaderboard)
+	if last, ok := guildState.db.LastSnapshot(); ok && maps.Equal(last.Scores, scores) {
+		return nil
+	}
+
+	log.Printf("Taking a snapshot of %d scores\n", len(scores))
+	return guildState.db.Snapshot(timestamp.Unix(), scores)
+}
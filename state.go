@@ -3,7 +3,13 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ErrNotConfigured is returned when a guild is not configured
@@ -12,43 +18,233 @@ var ErrNotConfigured = errors.New("guild is not configured")
 // ErrAlreadyClaimed is returned when a user is already claimed
 var ErrAlreadyClaimed = errors.New("user is already claimed")
 
+// ErrHasClaim is returned when a user who already claimed an account asks for another one
+var ErrHasClaim = errors.New("user has already claimed an account")
+
 // ErrDoesNotExist is returned when a user does not exist
 var ErrDoesNotExist = errors.New("user does not exist")
 
+// ErrNoAdminChannel is returned when a guild needs an admin channel but doesn't have one
+var ErrNoAdminChannel = errors.New("guild has no admin channel")
+
+// ErrInvalidDays is returned when a guild overrides the length of its event with one that has no tier roles
+var ErrInvalidDays = errors.New("days must be between 2 and 25")
+
 // ErrInvalidSession is returned when the advent of code session is invalid
 var ErrInvalidSession = errors.New("advent of code session has expired, please update the session cookie")
 
 // GuildState keeps track of the state of a single guild
 type GuildState struct {
-	adventOfCode *AdventOfCode
-	db           *Database
-	year         string
-	daily_roles  bool
+	sync.Mutex
+
+	adventOfCode   *AdventOfCode
+	leaderboardIDs []string
+	db             *Database
+	scorer         Scorer
+	daily_roles    bool
+
+	// The session its leaderboards are fetched with, a guild moves to its own when an admin gives it a cookie, see
+	// Bot.onSessionSubmit
+	session atomic.Pointer[string]
+
+	// The year the guild follows, replaced as a whole when it moves on, see StartSeason
+	current atomic.Pointer[yearState]
+	// Stops listening to the current year's leaderboards, see Bot.subscribe
+	unsubscribe func()
+
+	// Set when the year follows the calendar, see Bot.rollover
+	followCalendar bool
+	// The configured number of days, 0 derives it from the year
+	fixedDays int
+
+	announceChannelID string
+	moversReport      string
+	adminChannelID    string
+	verifyClaims      bool
+	claimApproval     bool
+
+	// Set while Advent of Code rejects the session cookie, see Bot.refreshGuild
+	degraded atomic.Bool
+
+	// The leaderboard as of the last call to Changes, and which of the guild's leaderboards it was merged from
+	lastSeen       *Leaderboard
+	lastSeenBoards map[string]bool
+
+	// Merged leaderboards by year, see cachedLeaderboard
+	mergedLock sync.Mutex
+	merged     map[string]mergedLeaderboard
+}
+
+// mergedLeaderboard is a merged leaderboard along with the leaderboards it was merged from
+type mergedLeaderboard struct {
+	sources     []*Leaderboard
+	leaderboard *Leaderboard
+}
+
+// yearState is everything about a guild that depends on the years it follows
+type yearState struct {
+	year string
+	// The current year first, then past years from newest to oldest
+	years []string
+	days  int
+	roles *RoleRegistry
 }
 
 // NewGuildState creates a new guild state
-func NewGuildState(sessionCookie string, config GuildConfig, log *os.File) (*GuildState, error) {
-	database, err := NewDatabase(log, log)
+//
+// A guild with its own session cookie gets its own session, named after the guild
+func NewGuildState(adventOfCode *AdventOfCode, guildID string, config GuildConfig, logFile *os.File) (*GuildState, error) {
+	scorer, err := NewScorer(config.Mode)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GuildState{
-		adventOfCode: NewAdventOfCode(sessionCookie, config.LeaderboardID),
-		db:           database,
-		year:         config.Year,
-		daily_roles:  config.DailyRoles,
-	}, nil
+	if _, ok := moverPeriods[config.MoversReport]; config.MoversReport != "" && !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPeriod, config.MoversReport)
+	}
+
+	if config.Days != 0 && (config.Days < 2 || config.Days > 25) {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidDays, config.Days)
+	}
+
+	if config.ClaimApproval && config.AdminChannelID == "" {
+		return nil, fmt.Errorf("%w: claim_approval posts claims to the admin channel", ErrNoAdminChannel)
+	}
+
+	database, err := NewDatabase(logFile, logFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge both ways of configuring leaderboards
+	var leaderboardIDs []string
+	for _, id := range append([]string{config.LeaderboardID}, config.LeaderboardIDs...) {
+		if id != "" && !slices.Contains(leaderboardIDs, id) {
+			leaderboardIDs = append(leaderboardIDs, id)
+		}
+	}
+
+	if len(leaderboardIDs) == 0 {
+		return nil, ErrNotConfigured
+	}
+
+	year := config.Year
+	pastYears := config.PastYears
+	seasons := database.GetSeasons()
+	now := time.Now()
+	if year == AutoYear {
+		// Pick up where the last season left off, the years before it are followed as past years
+		year = ActiveYear(now)
+		for _, season := range seasons {
+			year = season.Year
+			pastYears = append(pastYears, season.Previous)
+		}
+	}
+
+	session := ""
+	if config.SessionCookie != "" {
+		session = guildID
+		adventOfCode.SetSession(session, config.SessionCookie)
+	}
+
+	guildState := &GuildState{
+		adventOfCode:   adventOfCode,
+		leaderboardIDs: leaderboardIDs,
+		db:             database,
+		scorer:         scorer,
+		daily_roles:    config.DailyRoles,
+		followCalendar: config.Year == AutoYear,
+		fixedDays:      config.Days,
+
+		announceChannelID: config.AnnounceChannelID,
+		moversReport:      config.MoversReport,
+		adminChannelID:    config.AdminChannelID,
+		verifyClaims:      config.VerifyClaims,
+		claimApproval:     config.ClaimApproval,
+
+		merged: make(map[string]mergedLeaderboard),
+	}
+	guildState.session.Store(&session)
+
+	// A guild that has never rolled over only follows the active year once Advent of Code confirms it has started,
+	// until then it follows the year before and rollover moves it on
+	if config.Year == AutoYear && len(seasons) == 0 {
+		leaderboard := guildState.GetYearLeaderboard(year)
+		if leaderboard == nil || leaderboard.Event != year {
+			previous := ActiveYear(now.AddDate(-1, 0, 0))
+			log.Printf("Advent of Code %s hasn't started yet for %s, following %s\n", year, guildID, previous)
+			year = previous
+		}
+	}
+
+	guildState.current.Store(guildState.newYearState(year, pastYears))
+
+	return guildState, nil
 }
 
-// ClaimName claims a user by Advent of Code name
-func (guildState *GuildState) ClaimName(discordUserID string, username string) error {
-	member, ok := guildState.GetLeaderboard().GetMemberByName(username)
-	if !ok {
-		// Update the leaderboard and try again
-		member, ok = guildState.UpdateLeaderboard().GetMemberByName(username)
+// newYearState builds the state of a guild that follows a year as its current year, along with the given past years
+//
+// The managed roles are redefined, since the length of the event and the trophies depend on the years
+func (guildState *GuildState) newYearState(year string, pastYears []string) *yearState {
+	var years []string
+	for _, past := range pastYears {
+		if past != "" && past != year && !slices.Contains(years, past) {
+			years = append(years, past)
+		}
+	}
+	slices.Sort(years)
+	slices.Reverse(years)
+
+	state := &yearState{
+		year:  year,
+		years: append([]string{year}, years...),
+		days:  guildState.eventDays(year, year),
+	}
+
+	trophies := make(map[string]int, len(state.years))
+	for _, past := range state.years {
+		trophies[past] = 2 * guildState.eventDays(year, past)
+	}
+
+	state.roles = NewRoleRegistry(guildState.db, state.days, guildState.daily_roles, trophies, guildState.scorer)
+	return state
+}
+
+// eventDays gets the number of days in a year's event, the guild's override only applies to the current year
+//
+// Past years ran for as long as they ran, so their trophies keep asking for every star of the real event
+func (guildState *GuildState) eventDays(current, year string) int {
+	if guildState.fixedDays != 0 && year == current {
+		return guildState.fixedDays
+	}
+
+	return EventDays(year)
+}
+
+// StartSeason moves the guild on to a new event year, the previous year is still followed as a past year
+//
+// Progress already made in the new year is taken as the starting point, rather than announced
+func (guildState *GuildState) StartSeason(season EventSeason) error {
+	err := guildState.db.StartSeason(season)
+	if err != nil {
+		return err
 	}
 
+	// Swapped along with the baseline, so that Changes never diffs one year against another
+	guildState.Lock()
+	guildState.current.Store(guildState.newYearState(season.Year, guildState.current.Load().years))
+	guildState.lastSeen = guildState.cachedLeaderboard(season.Year)
+	guildState.lastSeenBoards = guildState.loadedBoards(season.Year)
+	guildState.Unlock()
+
+	return nil
+}
+
+// ClaimName claims a user by Advent of Code name
+func (guildState *GuildState) ClaimName(discordUserID string, username string) error {
+	member, ok := guildState.FindMember(func(leaderboard *Leaderboard) (*Member, bool) {
+		return leaderboard.GetMemberByName(username)
+	})
 	if !ok {
 		return ErrDoesNotExist
 	}
@@ -65,12 +261,9 @@ func (guildState *GuildState) ClaimName(discordUserID string, username string) e
 
 // ClaimID claims a user by Advent of Code ID
 func (guildState *GuildState) ClaimID(discordUserID string, id string) error {
-	member, ok := guildState.GetLeaderboard().GetMemberByID(id)
-	if !ok {
-		// Update the leaderboard and try again
-		member, ok = guildState.UpdateLeaderboard().GetMemberByID(id)
-	}
-
+	member, ok := guildState.FindMember(func(leaderboard *Leaderboard) (*Member, bool) {
+		return leaderboard.GetMemberByID(id)
+	})
 	if !ok {
 		return ErrDoesNotExist
 	}
@@ -85,6 +278,50 @@ func (guildState *GuildState) ClaimID(discordUserID string, id string) error {
 	return guildState.db.Claim(discordUserID, id)
 }
 
+// FindClaimable finds the member a Discord user wants to claim, by Advent of Code name or ID
+//
+// Members that are already claimed are returned along with ErrAlreadyClaimed
+func (guildState *GuildState) FindClaimable(username string) (*Member, error) {
+	member, ok := guildState.FindAccount(username)
+	if !ok {
+		return nil, ErrDoesNotExist
+	}
+
+	// Check if the user is already claimed
+	if guildState.db.CheckClaim(fmt.Sprint(member.ID)) {
+		return member, ErrAlreadyClaimed
+	}
+
+	return member, nil
+}
+
+// FindAccount finds a member by Advent of Code name, or by ID if no one has that name
+func (guildState *GuildState) FindAccount(username string) (*Member, bool) {
+	member, ok := guildState.FindMember(func(leaderboard *Leaderboard) (*Member, bool) {
+		return leaderboard.GetMemberByName(username)
+	})
+	if !ok {
+		member, ok = guildState.FindMember(func(leaderboard *Leaderboard) (*Member, bool) {
+			return leaderboard.GetMemberByID(username)
+		})
+	}
+
+	return member, ok
+}
+
+// FindMember looks for a member in each year the guild follows, newest first
+//
+// Claims belong to an Advent of Code account rather than a year, so someone who only played a past year can claim too
+func (guildState *GuildState) FindMember(find func(leaderboard *Leaderboard) (*Member, bool)) (*Member, bool) {
+	for _, year := range guildState.current.Load().years {
+		if member, ok := find(guildState.GetYearLeaderboard(year)); ok {
+			return member, true
+		}
+	}
+
+	return nil, false
+}
+
 // Unclaim removes a claim from a user by Discord ID
 func (guildState *GuildState) Unclaim(discordUserID string) error {
 	return guildState.db.Unclaim(discordUserID)
@@ -92,16 +329,206 @@ func (guildState *GuildState) Unclaim(discordUserID string) error {
 
 // CloseNames gets a list of 3 close names to the given name
 func (guildState *GuildState) CloseNames(username string) ([]string, error) {
-	return guildState.GetLeaderboard().CloseNames(username)
+	leaderboard := guildState.GetLeaderboard()
+	if leaderboard == nil {
+		return nil, ErrDoesNotExist
+	}
+
+	return leaderboard.CloseNames(username)
+}
+
+// maxSuggestions is the most autocomplete choices Discord accepts
+const maxSuggestions = 25
+
+// SuggestClaims suggests unclaimed members whose names are close to what has been typed so far
+//
+// Autocomplete runs on every keystroke, so only cached leaderboards are searched. Names that contain the input come
+// first, then the rest, each ordered by edit distance.
+func (guildState *GuildState) SuggestClaims(input string) []*Member {
+	claimed := guildState.db.GetDiscordIDs()
+
+	byName := make(map[string][]*Member)
+	seen := make(map[string]bool)
+	for _, year := range guildState.current.Load().years {
+		leaderboard := guildState.cachedLeaderboard(year)
+		if leaderboard == nil {
+			continue
+		}
+
+		for id, member := range leaderboard.Members {
+			if _, ok := claimed[id]; ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			name := member.DisplayName()
+			byName[name] = append(byName[name], member)
+		}
+	}
+
+	var matches, others []string
+	for name := range byName {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(input)) {
+			matches = append(matches, name)
+		} else {
+			others = append(others, name)
+		}
+	}
+	slices.Sort(matches)
+	slices.Sort(others)
+
+	names := matches
+	if input != "" {
+		closest, err := closestNames(input, matches, maxSuggestions)
+		if err != nil {
+			log.Println("Error (SuggestClaims) ranking names: ", err)
+		}
+
+		rest, err := closestNames(input, others, maxSuggestions-len(closest))
+		if err != nil {
+			log.Println("Error (SuggestClaims) ranking names: ", err)
+		}
+
+		names = append(closest, rest...)
+	}
+
+	var suggestions []*Member
+	for _, name := range names {
+		suggestions = append(suggestions, byName[name]...)
+	}
+
+	return suggestions[:min(len(suggestions), maxSuggestions)]
 }
 
-// GetLeaderboard is wrapper for guildState.adventOfCode.GetLeaderboard()
+// Session gets the session the guild's leaderboards are fetched with, the global session is ""
+func (guildState *GuildState) Session() string {
+	return *guildState.session.Load()
+}
+
+// GetLeaderboard gets the guild's leaderboard for the current year, refreshing it if it is due
 func (guildState *GuildState) GetLeaderboard() *Leaderboard {
-	return guildState.adventOfCode.GetLeaderboard(guildState.year)
+	return guildState.GetYearLeaderboard(guildState.current.Load().year)
+}
+
+// GetYearLeaderboard gets the guild's leaderboard for a year, refreshing it if it is due
+//
+// Guilds that follow several leaderboards get them merged into one, see MergeLeaderboards
+func (guildState *GuildState) GetYearLeaderboard(year string) *Leaderboard {
+	session := guildState.Session()
+	for _, id := range guildState.leaderboardIDs {
+		guildState.adventOfCode.GetLeaderboard(session, id, year)
+	}
+
+	return guildState.cachedLeaderboard(year)
+}
+
+// cachedLeaderboard gets the guild's leaderboard for a year without refreshing it
+//
+// Every update replaces a leaderboard rather than changing it, so a merged leaderboard is kept until one of the
+// leaderboards it was merged from is updated. Callers must not modify it.
+func (guildState *GuildState) cachedLeaderboard(year string) *Leaderboard {
+	session := guildState.Session()
+	if len(guildState.leaderboardIDs) == 1 {
+		return guildState.adventOfCode.Cached(session, guildState.leaderboardIDs[0], year)
+	}
+
+	leaderboards := make([]*Leaderboard, 0, len(guildState.leaderboardIDs))
+	for _, id := range guildState.leaderboardIDs {
+		leaderboards = append(leaderboards, guildState.adventOfCode.Cached(session, id, year))
+	}
+
+	guildState.mergedLock.Lock()
+	defer guildState.mergedLock.Unlock()
+
+	merged, ok := guildState.merged[year]
+	if !ok || !slices.Equal(merged.sources, leaderboards) {
+		merged = mergedLeaderboard{leaderboards, MergeLeaderboards(leaderboards...)}
+		guildState.merged[year] = merged
+	}
+
+	return merged.leaderboard
+}
+
+// FollowsYear checks if the guild follows a year, either as the current year or a past one
+func (guildState *GuildState) FollowsYear(year string) bool {
+	return slices.Contains(guildState.current.Load().years, year)
+}
+
+// Refresh refreshes each of the guild's leaderboards for the current year that is due
+//
+// Past years rarely change, so they are only fetched when someone asks for them, see RefreshYear
+func (guildState *GuildState) Refresh() error {
+	return guildState.RefreshYear(guildState.current.Load().year)
+}
+
+// RefreshYear refreshes each of the guild's leaderboards for a year that is due
+func (guildState *GuildState) RefreshYear(year string) error {
+	session := guildState.Session()
+
+	var errs []error
+	for _, id := range guildState.leaderboardIDs {
+		errs = append(errs, guildState.adventOfCode.Refresh(session, id, year))
+	}
+
+	return errors.Join(errs...)
 }
 
-// UpdateLeaderboard updates the leaderboard before returning it
-func (guildState *GuildState) UpdateLeaderboard() *Leaderboard {
-	guildState.adventOfCode.UpdateLeaderboard(guildState.year)
-	return guildState.adventOfCode.GetLeaderboard(guildState.year)
+// LeaderboardStatus reports when the guild's leaderboard for a year was last fetched, and whether any of it is stale
+//
+// When the guild follows several leaderboards, the oldest fetch is reported
+func (guildState *GuildState) LeaderboardStatus(year string) (time.Time, bool) {
+	session := guildState.Session()
+	var oldest time.Time
+	anyStale := false
+	for _, id := range guildState.leaderboardIDs {
+		fetched, stale := guildState.adventOfCode.Status(session, id, year)
+		if oldest.IsZero() || fetched.Before(oldest) {
+			oldest = fetched
+		}
+		anyStale = anyStale || stale
+	}
+
+	return oldest, anyStale
+}
+
+// Changes diffs the guild's leaderboard against what it was the last time Changes was called
+//
+// Diffing the merged leaderboard means a member on several leaderboards only produces each event once. A leaderboard
+// that couldn't be loaded before isn't news once it is, its members are taken as they are.
+func (guildState *GuildState) Changes() []LeaderboardEvent {
+	guildState.Lock()
+	defer guildState.Unlock()
+
+	year := guildState.current.Load().year
+	current := guildState.cachedLeaderboard(year)
+	loaded := guildState.loadedBoards(year)
+
+	session := guildState.Session()
+	baseline := guildState.lastSeen
+	for id := range loaded {
+		if baseline != nil && !guildState.lastSeenBoards[id] {
+			baseline = MergeLeaderboards(baseline, guildState.adventOfCode.Cached(session, id, year))
+		}
+	}
+
+	events := DiffLeaderboards(baseline, current)
+	if current != nil {
+		guildState.lastSeen = current
+		guildState.lastSeenBoards = loaded
+	}
+
+	return events
+}
+
+// loadedBoards gets the ids of the guild's leaderboards for a year that have been loaded, from the API or the cache
+func (guildState *GuildState) loadedBoards(year string) map[string]bool {
+	session := guildState.Session()
+	loaded := make(map[string]bool, len(guildState.leaderboardIDs))
+	for _, id := range guildState.leaderboardIDs {
+		if guildState.adventOfCode.Cached(session, id, year) != nil {
+			loaded[id] = true
+		}
+	}
+
+	return loaded
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testGuildState creates a guild state that follows the given leaderboards for a year, without a database
+func testGuildState(aoc *AdventOfCode, year string, leaderboardIDs ...string) *GuildState {
+	guildState := &GuildState{
+		adventOfCode:   aoc,
+		leaderboardIDs: leaderboardIDs,
+		merged:         make(map[string]mergedLeaderboard),
+	}
+	guildState.session.Store(new(string))
+	guildState.current.Store(&yearState{year: year, years: []string{year}})
+	return guildState
+}
+
+// setCached stores a leaderboard as if it was just fetched, without notifying anyone
+func setCached(aoc *AdventOfCode, id string, leaderboard *Leaderboard) {
+	now := time.Now()
+
+	aoc.Lock()
+	aoc.leaderboards[leaderboardKey{"", id, leaderboard.Event}] = &cachedLeaderboard{leaderboard: leaderboard, fetched: now, attempted: now}
+	aoc.Unlock()
+}
+
+func TestChangesLateLeaderboard(t *testing.T) {
+	aoc := NewAdventOfCode("", "", t.TempDir())
+	guildState := testGuildState(aoc, "2024", "a", "b")
+
+	setCached(aoc, "a", testLeaderboard("2024", testMember(1, "alice", star{1, 1, 100})))
+	if events := guildState.Changes(); len(events) != 0 {
+		t.Fatalf("the first Changes() = %v, want no events", events)
+	}
+
+	// b failed to load at first, its members aren't news once it loads. Only alice's new star is.
+	setCached(aoc, "b", testLeaderboard("2024",
+		testMember(2, "bob", star{1, 1, 50}, star{1, 2, 60}),
+		testMember(1, "alice", star{1, 1, 100}),
+	))
+	setCached(aoc, "a", testLeaderboard("2024", testMember(1, "alice", star{1, 1, 100}, star{1, 2, 200})))
+
+	want := []LeaderboardEvent{StarEvent{ID: "1", Name: "alice", Day: 1, Part: 2, Timestamp: 200}}
+	if events := guildState.Changes(); !reflect.DeepEqual(events, want) {
+		t.Errorf("Changes() = %v, want %v", events, want)
+	}
+
+	// From then on b is diffed like any other leaderboard
+	setCached(aoc, "b", testLeaderboard("2024",
+		testMember(2, "bob", star{1, 1, 50}, star{1, 2, 60}, star{2, 1, 300}),
+		testMember(1, "alice", star{1, 1, 100}),
+	))
+
+	want = []LeaderboardEvent{StarEvent{ID: "2", Name: "bob", Day: 2, Part: 1, Timestamp: 300}}
+	if events := guildState.Changes(); !reflect.DeepEqual(events, want) {
+		t.Errorf("Changes() = %v, want %v", events, want)
+	}
+}
+
+func TestCachedLeaderboardMergesOncePerUpdate(t *testing.T) {
+	aoc := NewAdventOfCode("", "", t.TempDir())
+	guildState := testGuildState(aoc, "2024", "a", "b")
+
+	setCached(aoc, "a", testLeaderboard("2024", testMember(1, "alice")))
+	setCached(aoc, "b", testLeaderboard("2024", testMember(2, "bob")))
+
+	first := guildState.cachedLeaderboard("2024")
+	if second := guildState.cachedLeaderboard("2024"); second != first {
+		t.Error("the leaderboards were merged again without being updated")
+	}
+
+	setCached(aoc, "b", testLeaderboard("2024", testMember(2, "bob"), testMember(3, "carol")))
+	if updated := guildState.cachedLeaderboard("2024"); updated == first || len(updated.Members) != 3 {
+		t.Errorf("the merged leaderboard wasn't updated along with b, it has %d members", len(updated.Members))
+	}
+}
+
+func TestDesiredRolesUsesScorer(t *testing.T) {
+	aoc := NewAdventOfCode("", "", t.TempDir())
+	guildState := testGuildState(aoc, "2025", "a")
+	guildState.scorer = LocalScorer{}
+
+	// Alice and bob have as many stars, but not as many points
+	alice := testMember(1, "alice", star{1, 1, 100}, star{1, 2, 200}, star{2, 1, 300}, star{2, 2, 400})
+	alice.LocalScore = 20
+	bob := testMember(2, "bob", star{1, 1, 150}, star{1, 2, 250}, star{2, 1, 350}, star{2, 2, 450})
+	bob.LocalScore = 8
+	leaderboard := testLeaderboard("2025", alice, bob, testMember(3, "carol"))
+	setCached(aoc, "a", leaderboard)
+
+	state := &yearState{year: "2025", years: []string{"2025"}, days: 12}
+	scores := guildState.scorer.Scores(leaderboard)
+
+	// With 3 members the local score tiers are 8, 16, 24, ...
+	for _, test := range []struct {
+		member *Member
+		want   []string
+	}{
+		{alice, []string{ConnectedRoleKey, StarsRoleKey(4), StarsRoleKey(8)}},
+		{bob, []string{ConnectedRoleKey, StarsRoleKey(4)}},
+		{leaderboard.Members["3"], []string{ConnectedRoleKey}},
+	} {
+		desired := guildState.DesiredRoles(state, test.member, scores)
+		if got := slices.Sorted(maps.Keys(desired)); !slices.Equal(got, test.want) {
+			t.Errorf("DesiredRoles() of %s = %v, want %v", test.member.Name, got, test.want)
+		}
+	}
+}
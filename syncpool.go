@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSyncInProgress is returned when a guild sync is started while the previous one is still running
+var ErrSyncInProgress = errors.New("a sync is already running for this guild")
+
+// The number of sync workers when none is configured
+const defaultSyncConcurrency = 4
+
+// SyncPool runs role syncs on a bounded number of workers, shared by every guild
+//
+// Every worker shares the Discord session, whose rate limiter queues requests per route bucket and retries
+// when Discord returns 429, so extra workers wait on the bucket instead of failing.
+type SyncPool struct {
+	sync.Mutex
+
+	// The workers take their jobs from here, see work
+	tasks chan syncTask
+
+	// Guild ids with a sync in progress
+	running map[string]bool
+
+	// Jobs queued by guild id, run once the guild's sync in progress finishes, see Queue
+	pending map[string][]func() error
+
+	// Counts rate limits reported by Discord, see Bot.onRateLimit
+	rateLimits atomic.Int64
+}
+
+// syncTask is a job of a sync, along with where the sync waits for it
+type syncTask struct {
+	job  func() error
+	errs chan<- error
+	wg   *sync.WaitGroup
+}
+
+// SyncReport summarizes a guild sync
+type SyncReport struct {
+	GuildID     string
+	Jobs        int
+	Errors      []error
+	RateLimited int64
+	Duration    time.Duration
+}
+
+func (report SyncReport) String() string {
+	return fmt.Sprintf("synced %d members of %s in %s (%d errors, %d rate limits)",
+		report.Jobs-len(report.Errors), report.GuildID, report.Duration.Round(time.Millisecond), len(report.Errors), report.RateLimited)
+}
+
+// NewSyncPool creates a sync pool and starts its workers, a concurrency of 0 uses the default
+func NewSyncPool(concurrency int) *SyncPool {
+	if concurrency <= 0 {
+		concurrency = defaultSyncConcurrency
+	}
+
+	pool := &SyncPool{
+		tasks:   make(chan syncTask),
+		running: make(map[string]bool),
+		pending: make(map[string][]func() error),
+	}
+
+	for range concurrency {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// work runs jobs for as long as the bot runs
+func (pool *SyncPool) work() {
+	for task := range pool.tasks {
+		if err := task.job(); err != nil {
+			task.errs <- err
+		}
+		task.wg.Done()
+	}
+}
+
+// Run runs every job for a guild on the pool's workers and waits for them to finish
+//
+// Only one sync per guild may run at a time, ErrSyncInProgress is returned instead of starting another
+func (pool *SyncPool) Run(guildID string, jobs []func() error) (SyncReport, error) {
+	pool.Lock()
+	if pool.running[guildID] {
+		pool.Unlock()
+		return SyncReport{GuildID: guildID, Jobs: len(jobs)}, ErrSyncInProgress
+	}
+	pool.running[guildID] = true
+	pool.Unlock()
+
+	report := pool.run(guildID, jobs)
+	pool.runPending(guildID)
+
+	return report, nil
+}
+
+// Queue hands every job for a guild to the pool's workers without waiting for them, when the guild is already syncing
+// the jobs are queued instead
+//
+// Queued jobs are run by the sync in progress once it finishes, so a change that happens during a sync isn't lost
+func (pool *SyncPool) Queue(guildID string, jobs []func() error) {
+	pool.Lock()
+	if pool.running[guildID] {
+		pool.pending[guildID] = append(pool.pending[guildID], jobs...)
+		pool.Unlock()
+
+		log.Printf("Queued %d jobs for %s behind the sync in progress\n", len(jobs), guildID)
+		return
+	}
+	pool.running[guildID] = true
+	pool.Unlock()
+
+	go func() {
+		pool.run(guildID, jobs)
+		pool.runPending(guildID)
+	}()
+}
+
+// runPending runs the jobs queued for a guild until there are none left, and then marks its sync as finished
+func (pool *SyncPool) runPending(guildID string) {
+	for {
+		pool.Lock()
+		jobs := pool.pending[guildID]
+		delete(pool.pending, guildID)
+		if len(jobs) == 0 {
+			delete(pool.running, guildID)
+			pool.Unlock()
+			return
+		}
+		pool.Unlock()
+
+		pool.run(guildID, jobs)
+	}
+}
+
+// run runs every job on the pool's workers and waits for them to finish
+func (pool *SyncPool) run(guildID string, jobs []func() error) SyncReport {
+	report := SyncReport{GuildID: guildID, Jobs: len(jobs)}
+
+	start := time.Now()
+	rateLimits := pool.rateLimits.Load()
+
+	errs := make(chan error, len(jobs))
+
+	var wg sync.WaitGroup
+	wg.Add(len(jobs))
+	for _, job := range jobs {
+		pool.tasks <- syncTask{job, errs, &wg}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		report.Errors = append(report.Errors, err)
+	}
+	report.Duration = time.Since(start)
+	report.RateLimited = pool.rateLimits.Load() - rateLimits
+
+	log.Printf("Sync finished: %s\n", report)
+	return report
+}
+
+// RateLimited records that Discord rate limited a request
+func (pool *SyncPool) RateLimited() {
+	pool.rateLimits.Add(1)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor fails the test if a channel isn't closed or sent to in time
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestSyncPoolQueue(t *testing.T) {
+	pool := NewSyncPool(2)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ran := make(chan struct{}, 2)
+
+	done := make(chan SyncReport)
+	go func() {
+		report, err := pool.Run("guild", []func() error{func() error {
+			close(started)
+			<-release
+			return nil
+		}})
+		if err != nil {
+			t.Errorf("Run() returned %v", err)
+		}
+		done <- report
+	}()
+	waitFor(t, started, "the sync to start")
+
+	// A second sync is turned away, but queued jobs are run by the sync in progress
+	if _, err := pool.Run("guild", nil); !errors.Is(err, ErrSyncInProgress) {
+		t.Errorf("Run() during a sync returned %v, want ErrSyncInProgress", err)
+	}
+
+	pool.Queue("guild", []func() error{func() error {
+		ran <- struct{}{}
+		return nil
+	}})
+
+	select {
+	case <-ran:
+		t.Error("the queued job ran before the sync in progress finished")
+	default:
+	}
+	close(release)
+
+	if report := <-done; report.Jobs != 1 {
+		t.Errorf("the report counts %d jobs, want only the 1 it was started with", report.Jobs)
+	}
+	waitFor(t, ran, "the queued job")
+
+	// Once the guild is done syncing, queued jobs run right away
+	pool.Queue("guild", []func() error{func() error {
+		ran <- struct{}{}
+		return errors.New("failed")
+	}})
+	waitFor(t, ran, "the job queued after the sync")
+}
+
+func TestSyncPoolSharesWorkers(t *testing.T) {
+	pool := NewSyncPool(1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ran := make(chan struct{})
+
+	// Queue returns without waiting for the jobs, it is called from the leaderboard listeners
+	pool.Queue("a", []func() error{func() error {
+		close(started)
+		<-release
+		return nil
+	}})
+	waitFor(t, started, "the sync of a to start")
+
+	pool.Queue("b", []func() error{func() error {
+		close(ran)
+		return nil
+	}})
+
+	// Another guild's sync waits for the only worker
+	select {
+	case <-ran:
+		t.Fatal("b synced while a had the only worker")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	waitFor(t, ran, "the sync of b")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TeardownReport summarizes what Teardown removed
+type TeardownReport struct {
+	Members  int
+	Roles    int
+	Channels int
+	Errors   int
+
+	// Spoiler channels whose @everyone permissions couldn't be restored, see EventChannel
+	Unknown int
+}
+
+func (report TeardownReport) String() string {
+	msg := fmt.Sprintf("Removed managed roles from %d members, deleted %d roles and reverted %d spoiler channels.", report.Members, report.Roles, report.Channels)
+	if report.Unknown > 0 {
+		msg += fmt.Sprintf(" %d of those channels were set up before I recorded their permissions, so check who can see them.", report.Unknown)
+	}
+	if report.Errors > 0 {
+		msg += fmt.Sprintf(" %d steps failed, check the logs.", report.Errors)
+	}
+	return msg
+}
+
+// Teardown removes everything the bot created in a guild
+//
+// Managed roles are stripped from every member and deleted, and spoiler channels get their @everyone
+// permissions back. Claims and trophies are kept, trophies are earned once and can't be earned again.
+func (bot *Bot) Teardown(guild *discordgo.Guild) (TeardownReport, error) {
+	var report TeardownReport
+
+	guildState, ok := bot.states[guild.ID]
+	if !ok {
+		return report, ErrNotConfigured
+	}
+
+	log.Printf("Tearing down %s\n", guild.Name)
+
+	// Strip managed roles from every member
+	after := ""
+	for {
+		members, err := bot.session.GuildMembers(guild.ID, after, 1000)
+		if err != nil {
+			return report, err
+		}
+
+		for _, member := range members {
+			if !bot.hasManagedRole(guildState, member) {
+				continue
+			}
+
+			err = bot.RemoveAllRoles(guild, member)
+			if err != nil {
+				log.Printf("Error (Teardown) removing roles from %s: %s\n", member.User.Username, err)
+				report.Errors++
+				continue
+			}
+			report.Members++
+		}
+
+		if len(members) < 1000 {
+			break
+		}
+		after = members[len(members)-1].User.ID
+	}
+
+	// Revert the spoiler channels
+	everyoneID := everyoneRoleID(guild)
+	for _, channel := range guildState.db.GetChannels() {
+		for _, key := range []string{DayRoleKey(channel.Day), SpoilerRoleKey} {
+			if roleID, ok := guildState.db.GetRoleID(key); ok {
+				_ = bot.session.ChannelPermissionDelete(channel.ChannelID, roleID)
+			}
+		}
+
+		// Without the original @everyone overwrite, the channel stays hidden rather than guessing
+		var err error
+		if channel.EveryoneUnknown {
+			log.Printf("Teardown left @everyone alone in channel %s, it was set up before its permissions were recorded\n", channel.ChannelID)
+			report.Unknown++
+		} else if channel.HadEveryone {
+			err = bot.session.ChannelPermissionSet(channel.ChannelID, everyoneID, discordgo.PermissionOverwriteTypeRole, channel.EveryoneAllow, channel.EveryoneDeny)
+		} else {
+			err = bot.session.ChannelPermissionDelete(channel.ChannelID, everyoneID)
+		}
+
+		if err != nil {
+			log.Printf("Error (Teardown) reverting channel %s: %s\n", channel.ChannelID, err)
+			report.Errors++
+			continue
+		}
+
+		channel.Removed = true
+		err = guildState.db.SetChannel(channel)
+		if err != nil {
+			return report, err
+		}
+		report.Channels++
+	}
+
+	// Delete the roles
+	for key, roleID := range guildState.db.GetRoleIDs() {
+		if isTrophyRoleKey(key) {
+			continue
+		}
+
+		if findRole(guild, roleID) != nil {
+			err := bot.session.GuildRoleDelete(guild.ID, roleID)
+			if err != nil {
+				log.Printf("Error (Teardown) deleting role %s: %s\n", key, err)
+				report.Errors++
+				continue
+			}
+			report.Roles++
+		}
+
+		err := guildState.db.SetRoleID(key, "")
+		if err != nil {
+			return report, err
+		}
+	}
+
+	log.Printf("Tore down %s: %s\n", guild.Name, report)
+	return report, nil
+}
+
+// hasManagedRole checks if a member has any role the bot created, other than trophies
+func (bot *Bot) hasManagedRole(guildState *GuildState, member *discordgo.Member) bool {
+	registry := guildState.current.Load().roles
+	for _, roleID := range member.Roles {
+		if key, ok := registry.Key(roleID); ok && !isTrophyRoleKey(key) {
+			return true
+		}
+	}
+	return false
+}
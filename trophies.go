@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// YearProgress is how far a member got in one year's event
+type YearProgress struct {
+	Year  string
+	Stars int
+	Total int
+}
+
+// Finished checks if every star of the year was collected, which earns that year's trophy
+func (progress YearProgress) Finished() bool {
+	return progress.Stars >= progress.Total
+}
+
+// Progress gets an Advent of Code account's progress in each year of state that it played, newest first
+//
+// The current year is already kept up to date and past years rarely change, so cached leaderboards are used unless
+// refresh is set. A year that was never fetched is fetched regardless.
+func (guildState *GuildState) Progress(state *yearState, adventID string, refresh bool) []YearProgress {
+	var progress []YearProgress
+	for _, year := range state.years {
+		leaderboard := guildState.cachedLeaderboard(year)
+		if refresh || leaderboard == nil {
+			leaderboard = guildState.GetYearLeaderboard(year)
+		}
+
+		member, ok := leaderboard.GetMemberByID(adventID)
+		if !ok {
+			continue
+		}
+
+		progress = append(progress, YearProgress{
+			Year:  year,
+			Stars: member.Stars,
+			Total: 2 * guildState.eventDays(state.year, year),
+		})
+	}
+
+	return progress
+}
+
+// renderTrophies lists the trophies a Discord user earned, along with how far they got in the years they didn't finish
+func (guildState *GuildState) renderTrophies(discordID string) (string, error) {
+	adventID, ok := guildState.db.GetAdventID(discordID)
+	if !ok {
+		return "", ErrDoesNotExist
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Trophies of <@%s>**\n", discordID)
+
+	progress := guildState.Progress(guildState.current.Load(), adventID, true)
+	if len(progress) == 0 {
+		sb.WriteString("They aren't on any of the leaderboards I follow.")
+		return sb.String(), nil
+	}
+
+	for _, year := range progress {
+		if year.Finished() {
+			fmt.Fprintf(&sb, ":trophy: AoC %s ⭐%d\n", year.Year, year.Total)
+		} else {
+			fmt.Fprintf(&sb, "AoC %s: %d/%d stars\n", year.Year, year.Stars, year.Total)
+		}
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long a user has to put their token in their Advent of Code display name
+const verificationTTL = 24 * time.Hour
+
+// StartVerification starts verifying that a Discord user owns an Advent of Code account, by name or ID
+//
+// The user proves it by adding the returned token to their display name, see Bot.checkVerifications. Starting a new
+// verification replaces the user's pending one. Users who already claimed an account have to `/unclaim` it first,
+// ErrHasClaim is returned instead.
+func (guildState *GuildState) StartVerification(discordUserID string, username string) (EventVerification, error) {
+	member, err := guildState.FindClaimable(username)
+	if err != nil {
+		return EventVerification{}, err
+	}
+
+	if _, ok := guildState.db.GetAdventID(discordUserID); ok {
+		return EventVerification{}, ErrHasClaim
+	}
+
+	if previous, ok := guildState.db.GetVerification(discordUserID); ok {
+		previous.Outcome = VerificationReplaced
+		err := guildState.db.SetVerification(previous)
+		if err != nil {
+			return EventVerification{}, err
+		}
+	}
+
+	verification := EventVerification{
+		DiscordID: discordUserID,
+		AdventID:  fmt.Sprint(member.ID),
+		Token:     "AOC-" + rand.Text()[:6],
+		Expires:   time.Now().Add(verificationTTL).Unix(),
+	}
+
+	return verification, guildState.db.SetVerification(verification)
+}
+
+// checkVerifications claims every account whose pending verification token is in its display name, and expires
+// verifications that ran out of time
+//
+// The current year is expected to be refreshed already, this should run right after the guild is. Past years are only
+// refreshed when an account being verified is on them, so its new display name is seen.
+func (bot *Bot) checkVerifications(guild *discordgo.Guild, guildState *GuildState, now time.Time) {
+	state := guildState.current.Load()
+	refreshed := map[string]bool{state.year: true}
+
+	for _, verification := range guildState.db.GetVerifications() {
+		var member *Member
+		for _, year := range state.years {
+			found, ok := guildState.cachedLeaderboard(year).GetMemberByID(verification.AdventID)
+			if !ok {
+				continue
+			}
+
+			if !refreshed[year] {
+				refreshed[year] = true
+				err := guildState.RefreshYear(year)
+				if err != nil {
+					log.Println("Error (checkVerifications) refreshing leaderboard: ", err)
+				}
+				found, _ = guildState.cachedLeaderboard(year).GetMemberByID(verification.AdventID)
+			}
+
+			member = found
+			break
+		}
+
+		var msg string
+		switch {
+		case member != nil && strings.Contains(strings.ToUpper(member.Name), verification.Token):
+			if guildState.db.CheckClaim(verification.AdventID) {
+				verification.Outcome = VerificationTaken
+				msg = fmt.Sprintf("I saw `%s` in %s's name, but someone else claimed that account first. Please contact an administrator.", verification.Token, member.DisplayName())
+				break
+			}
+
+			verification.Outcome = VerificationVerified
+			msg = fmt.Sprintf("Success: You have verified and claimed %s! You can change your Advent of Code display name back now.", member.DisplayName())
+
+			// Guilds that approve claims still get the final say
+			var err error
+			if guildState.claimApproval {
+				err = bot.requestClaim(guildState, verification.DiscordID, member)
+				msg = fmt.Sprintf("Success: You have verified %s! You can change your Advent of Code display name back now, I'll message you once an admin approves your claim.", member.DisplayName())
+			} else {
+				err = guildState.db.Claim(verification.DiscordID, verification.AdventID)
+			}
+
+			if err != nil {
+				log.Println("Error (checkVerifications) claiming: ", err)
+				continue
+			}
+		case now.Unix() > verification.Expires:
+			verification.Outcome = VerificationExpired
+			msg = fmt.Sprintf("Your claim expired before I saw `%s` in your Advent of Code display name, run `/claim` again to get a new one.", verification.Token)
+		default:
+			continue
+		}
+
+		log.Printf("Verification of %s for %s: %s\n", verification.AdventID, verification.DiscordID, verification.Outcome)
+		err := guildState.db.SetVerification(verification)
+		if err != nil {
+			log.Println("Error (checkVerifications) saving outcome: ", err)
+		}
+
+		err = bot.directMessage(verification.DiscordID, msg)
+		if err != nil {
+			log.Println("Error (checkVerifications) sending DM: ", err)
+		}
+
+		if verification.Outcome != VerificationVerified || guildState.claimApproval {
+			continue
+		}
+
+		guildMember, err := bot.session.GuildMember(guild.ID, verification.DiscordID)
+		if err != nil {
+			log.Println("Error (checkVerifications) getting guild member: ", err)
+			continue
+		}
+
+		err = bot.SyncMemberRoles(guild, guildMember)
+		if err != nil {
+			log.Println("Error (checkVerifications) syncing roles: ", err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStartVerification(t *testing.T) {
+	aoc := NewAdventOfCode("", "", t.TempDir())
+	guildState := testGuildState(aoc, "2024", "a")
+	guildState.db = replay(t, `{"create":{"discord_id":"d1","aoc_id":"1"}}`)
+
+	setCached(aoc, "a", testLeaderboard("2024", testMember(1, "alice"), testMember(2, "bob"), testMember(3, "carol")))
+
+	first, err := guildState.StartVerification("d2", "bob")
+	if err != nil {
+		t.Fatalf("StartVerification() returned %v", err)
+	}
+
+	if first.AdventID != "2" || !strings.HasPrefix(first.Token, "AOC-") {
+		t.Errorf("StartVerification() = %v, want a token for bob", first)
+	}
+
+	// Starting again replaces the pending verification
+	second, err := guildState.StartVerification("d2", "3")
+	if err != nil {
+		t.Fatalf("StartVerification() returned %v", err)
+	}
+
+	if pending, _ := guildState.db.GetVerification("d2"); pending != second {
+		t.Errorf("pending verification = %v, want %v", pending, second)
+	}
+
+	if _, err := guildState.StartVerification("d3", "alice"); !errors.Is(err, ErrAlreadyClaimed) {
+		t.Errorf("verifying a claimed account returned %v, want ErrAlreadyClaimed", err)
+	}
+
+	if _, err := guildState.StartVerification("d1", "bob"); !errors.Is(err, ErrHasClaim) {
+		t.Errorf("verifying for a user with a claim returned %v, want ErrHasClaim", err)
+	}
+
+	if _, err := guildState.StartVerification("d3", "dave"); !errors.Is(err, ErrDoesNotExist) {
+		t.Errorf("verifying a missing account returned %v, want ErrDoesNotExist", err)
+	}
+}